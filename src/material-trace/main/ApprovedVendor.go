@@ -0,0 +1,27 @@
+package main
+
+/*
+	Defines the customer qualification of a manufacturer to supply a material group,
+	optionally limited to a single spec e.g. API 5L X52
+*/
+type ApprovedVendor struct {
+	ObjectType         string `json:"docType"`
+	Manufacturer       string `json:"manufacturer"`
+	MaterialGroup      string `json:"materialGroup"`
+	Spec               string `json:"spec"` // empty when qualified for every spec of the group
+	Status             string `json:"status"`
+	QualifiedTimeStamp int64  `json:"qualifiedTimeStamp"`
+	ExpiryTimeStamp    int64  `json:"expiryTimeStamp"` // 0 when the qualification does not expire
+	Comment            string `json:"comment"`
+	UpdatedTimeStamp   int64  `json:"updatedTimeStamp"`
+}
+
+/*
+	Whether a manufacturer can supply a purchase order line and why
+*/
+type EligibleManufacturer struct {
+	Manufacturer    string `json:"manufacturer"`
+	Eligible        bool   `json:"eligible"`
+	Reason          string `json:"reason"`
+	ExpiryTimeStamp int64  `json:"expiryTimeStamp"`
+}
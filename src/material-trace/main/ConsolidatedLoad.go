@@ -0,0 +1,19 @@
+package main
+
+/*
+	Defines a load consolidating shipping requests of several POs on one truck under one IoT tracker.
+	The status is worked out from the shipping requests when the load is read.
+*/
+type ConsolidatedLoad struct {
+	ObjectType             string            `json:"docType"`
+	LoadId                 string            `json:"loadId"`
+	IotTrackingCode        string            `json:"iotTrackingCode"`
+	TrackingCodes          map[string]string `json:"trackingCodes"` // PO id to the tracking code its lines are recorded under
+	ShippingRequestNumbers []int64           `json:"shippingRequestNumbers"`
+	PoIds                  []string          `json:"poIds"`
+	ProjectIds             []string          `json:"projectIds"`
+	ConsolidatedBy         string            `json:"consolidatedBy"`
+	Status                 string            `json:"status"`
+	TimeConsolidated       int64             `json:"timeConsolidated"`
+	ShippingRequests       []ShippingRequest `json:"shippingRequests,omitempty"` // filled in when the load is read
+}
@@ -0,0 +1,27 @@
+package main
+
+/*
+	Defines a relationship between two nodes of the material genealogy,
+	e.g. heat -> material certificate -> order request -> shipment -> PO line -> project
+*/
+type GenealogyEdge struct {
+	ObjectType      string `json:"docType"`
+	FromType        string `json:"fromType"`
+	FromId          string `json:"fromId"`
+	ToType          string `json:"toType"`
+	ToId            string `json:"toId"`
+	PoId            string `json:"poId"`
+	IotTrackingCode string `json:"iotTrackingCode"`
+	RecordedBy      string `json:"recordedBy"`
+	TimeStamp       int64  `json:"timeStamp"`
+}
+
+/*
+	Result of a genealogy trace from a starting node
+*/
+type GenealogyTrace struct {
+	NodeType  string          `json:"nodeType"`
+	NodeId    string          `json:"nodeId"`
+	Direction string          `json:"direction"`
+	Edges     []GenealogyEdge `json:"edges"`
+}
@@ -0,0 +1,65 @@
+package main
+
+/*
+	Defines an inspection assigned to the third-party inspector, with a copy of only the data needed for it
+*/
+type InspectionAssignment struct {
+	ObjectType         string             `json:"docType"`
+	Id                 string             `json:"id"`
+	Inspector          string             `json:"inspector"`
+	TargetType         string             `json:"targetType"` // mtr or shippingRequest
+	TargetId           string             `json:"targetId"`   // tracking id or shipping request number
+	PoId               string             `json:"poId"`
+	LineNumbers        []int              `json:"lineNumbers"`
+	AttestationTypes   []string           `json:"attestationTypes"`
+	Mtr                *InspectionMtr     `json:"mtr,omitempty"`
+	ShippingLines      []ShippingLineItem `json:"shippingLines,omitempty"`
+	Status             string             `json:"status"`
+	Attestations       []string           `json:"attestations"`
+	SharedAttestations []string           `json:"sharedAttestations"` // attestations copied into the line progress
+	AssignedBy         string             `json:"assignedBy"`
+	AssignedTimeStamp  int64              `json:"assignedTimeStamp"`
+}
+
+/*
+	Certificate details an inspector needs to witness a mill test.
+	The certificate values stay in the manufacturer collection, the document hash identifies the certificate inspected.
+*/
+type InspectionMtr struct {
+	TrackingId     string `json:"trackingId"`
+	Revision       int    `json:"revision"`
+	MaterialGroup  string `json:"materialGroup"`
+	Spec           string `json:"spec"`
+	HeatNumber     string `json:"heatNumber"`
+	DocumentSha256 string `json:"documentSha256"`
+}
+
+/*
+	Defines an attestation recorded by an inspector against a certificate or a shipping request
+*/
+type Attestation struct {
+	ObjectType    string         `json:"docType"`
+	Id            string         `json:"id"`
+	AssignmentId  string         `json:"assignmentId"`
+	TargetType    string         `json:"targetType"`
+	TargetId      string         `json:"targetId"`
+	PoId          string         `json:"poId"`
+	LineNumbers   []int          `json:"lineNumbers"`
+	Type          string         `json:"type"`   // witnessed-test, visual-inspection or coating-check
+	Result        string         `json:"result"` // pass or fail
+	Remarks       string         `json:"remarks"`
+	Inspector     string         `json:"inspector"`
+	Signer        SignerIdentity `json:"signer"`
+	ContentSha256 string         `json:"contentSha256"`
+	TimeStamp     int64          `json:"timeStamp"`
+}
+
+/*
+	Identity of the client that signed the transaction recording a document
+*/
+type SignerIdentity struct {
+	MspId             string `json:"mspId"`
+	Subject           string `json:"subject"`
+	Issuer            string `json:"issuer"`
+	CertificateSha256 string `json:"certificateSha256"`
+}
@@ -0,0 +1,41 @@
+package main
+
+/*
+	Defines the stock the distributor holds for a material at a location
+*/
+type InventoryRecord struct {
+	ObjectType    string                 `json:"docType"`
+	MaterialId    string                 `json:"materialId"`
+	Location      string                 `json:"location"`
+	Description   string                 `json:"description"`
+	UnitOfMeasure string                 `json:"unitOfMeasure"`
+	OnHand        int                    `json:"onHand"`
+	Reserved      int                    `json:"reserved"`
+	Available     int                    `json:"available"`
+	Reservations  []InventoryReservation `json:"reservations"`
+	Transactions  []InventoryTransaction `json:"transactions"`
+}
+
+/*
+	Quantity held for a specific PO line item until it ships
+*/
+type InventoryReservation struct {
+	ItemKey   string `json:"itemKey"`
+	Quantity  int    `json:"quantity"`
+	TimeStamp int64  `json:"timeStamp"`
+}
+
+/*
+	A receipt, adjustment, reservation or issue recorded against an inventory record
+*/
+type InventoryTransaction struct {
+	Type          string `json:"type"`
+	MaterialId    string `json:"materialId"`
+	Location      string `json:"location"`
+	Description   string `json:"description"`
+	UnitOfMeasure string `json:"unitOfMeasure"`
+	Quantity      int    `json:"quantity"`
+	Reference     string `json:"reference"`
+	Reason        string `json:"reason"`
+	TimeStamp     int64  `json:"timeStamp"`
+}
@@ -11,6 +11,7 @@ type LineItem struct {
 	ItemKey               string         `json:"itemKey"`
 	Description           string         `json:"description"`
 	Quantity              int            `json:"quantity"`
+	ReturnedQuantity      int            `json:"returnedQuantity"` // quantity on open or closed RMAs
 	UnitOfMeasure         string         `json:"unitOfMeasure"`
 	UnitPrice             float64        `json:"unitPrice"`
 	Currency              string         `json:"currency"`
@@ -19,6 +20,8 @@ type LineItem struct {
 	ProjectId             string         `json:"projectId"`
 	DeliveryDate          string         `json:"deliveryDate"`
 	AssignedTo            string         `json:"assignedTo"`
+	Warehouse             string         `json:"warehouse"`  // set while the item is held by a warehouse
+	Compliance            string         `json:"compliance"` // verdict of the linked material certificates
 	Status                string         `json:"status"`
 	AssignedQty           int            `json:"assignedQty"`
 	MfrUnitPrice          float64        `json:"mfrUnitPrice"`
@@ -91,6 +94,9 @@ type LineItemPricing struct {
 	Quantity              int           `json:"quantity"`
 	UnitOfMeasure         string        `json:"unitOfMeasure"`
 	UnitPrice             float64       `json:"unitPrice"`
+	BaseQuantity          float64       `json:"baseQuantity"`
+	BaseUnitOfMeasure     string        `json:"baseUnitOfMeasure"`
+	BaseUnitPrice         float64       `json:"baseUnitPrice"`
 	Currency              string        `json:"currency"`
 	Subtotal              float64       `json:"subtotal"`
 	ShipToLocation        Company       `json:"shipToLocation"`
@@ -99,6 +105,7 @@ type LineItemPricing struct {
 	ProjectId             string        `json:"projectId"`
 	DeliveryDate          string        `json:"deliveryDate"`
 	MaterialCertificate   []Mtr         `json:"materialCertificate"`
+	Compliance            string        `json:"compliance"`
 	IotTrackingCode       string        `json:"iotTrackingCode"`
 	IotProperties         []IotProperty `json:"iotProperties"`
 	AcknowledgedTimeStamp int64         `json:"acknowledgedTimeStamp"`
@@ -107,7 +114,7 @@ type LineItemPricing struct {
 }
 
 /*
-   Defines structure for private data for logistics
+   Defines the former per PO structure for private data for logistics, kept to migrate existing records
 */
 type ShippingPrivateDetails struct {
 	ObjectType string             `json:"docType"` //docType is used to distinguish the various types of objects in state database
@@ -115,12 +122,27 @@ type ShippingPrivateDetails struct {
 	LineItems  []ShippingLineItem `json:"lineItems"`
 }
 
+/*
+   Defines a shipping request stored in the shipping collection under its number.
+   Carriers never read it, they work on the copy tendered to them.
+*/
 type ShippingRequest struct {
-	ShipToLocation        Company            `json:"shipToLocation"`
-	RequestedBy           string             `json:"requestedBy"`
+	ObjectType            string             `json:"docType"`
 	ShippingRequestNumber int64              `json:"shippingRequestNumber"`
+	PoId                  string             `json:"poId"`
+	PoNumber              int                `json:"poNumber"`
+	RequestedBy           string             `json:"requestedBy"`
+	ShipFrom              Company            `json:"shipFrom"`
+	ShipToLocation        Company            `json:"shipToLocation"`
 	IotTrackingCode       string             `json:"iotTrackingCode"`
+	Status                string             `json:"status"`
 	LineItems             []ShippingLineItem `json:"lineItems"`
+	TimeRequested         int64              `json:"timeRequested"`
+	TimeShipped           int64              `json:"timeShipped"`
+	TimeDelivered         int64              `json:"timeDelivered"`
+	TenderedTo            []string           `json:"tenderedTo,omitempty"` // carrier MSP ids
+	AwardedTo             string             `json:"awardedTo,omitempty"`  // carrier MSP id
+	LoadId                string             `json:"loadId,omitempty"`     // set when consolidated with other requests
 }
 
 /*
@@ -130,8 +152,11 @@ type ShippingLineItem struct {
 	PoId                  string       `json:"poId"`
 	PoNumber              int          `json:"poNumber"`
 	LineNumber            int          `json:"lineNumber"`
+	ItemKey               string       `json:"itemKey"`
 	Quantity              int          `json:"quantity"`
 	UnitOfMeasure         string       `json:"unitOfMeasure"`
+	BaseQuantity          float64      `json:"baseQuantity"`
+	BaseUnitOfMeasure     string       `json:"baseUnitOfMeasure"`
 	ShippingRequestNumber int64        `json:"shippingRequestNumber"`
 	MaterialId            string       `json:"materialId"` // don't think logistics need this, but needed current design
 	Description           string       `json:"description"`
@@ -143,6 +168,7 @@ type ShippingLineItem struct {
 	TimeRequested         int64        `json:"timeRequested"`
 	TimeShipped           int64        `json:"timeShipped"`
 	ProgressStatus        []ItemStatus `json:"progressStatus"`
+	RmaId                 string       `json:"rmaId"` // set when the shipment returns material under an RMA
 }
 
 /*
@@ -159,7 +185,10 @@ type SharedLineDetail struct {
 	LineNumber            int           `json:"lineNumber"`
 	ItemKey               string        `json:"itemKey"`
 	AssignedTo            string        `json:"assignedTo"`
+	Warehouse             string        `json:"warehouse"`
 	MaterialCertificate   []Mtr         `json:"materialCertificate"`
+	Compliance            string        `json:"compliance"`
+	MtrReviewRequired     bool          `json:"mtrReviewRequired"`
 	IotTrackingCode       string        `json:"iotTrackingCode"`
 	IotProperties         []IotProperty `json:"iotProperties"`
 	TimeShipped           int64         `json:"timeShipped"`
@@ -0,0 +1,39 @@
+package main
+
+/*
+	Defines an entry in the material master catalog shared by all organizations
+*/
+type Material struct {
+	ObjectType            string           `json:"docType"`
+	MaterialId            string           `json:"materialId"`
+	Description           string           `json:"description"`
+	MaterialGroup         string           `json:"materialGroup"`
+	BaseUnitOfMeasure     string           `json:"baseUnitOfMeasure"`
+	Spec                  MaterialSpec     `json:"spec"`
+	Conversions           []UnitConversion `json:"conversions"`
+	ApprovedManufacturers []string         `json:"approvedManufacturers"`
+	Active                bool             `json:"active"`
+	UpdatedBy             string           `json:"updatedBy"`
+	UpdatedTimeStamp      int64            `json:"updatedTimeStamp"`
+}
+
+/*
+	Engineering specification of a material e.g. API 5L X52 pipe
+*/
+type MaterialSpec struct {
+	Standard        string  `json:"standard"`
+	Grade           string  `json:"grade"`
+	OutsideDiameter float64 `json:"outsideDiameter"`
+	WallThickness   float64 `json:"wallThickness"`
+	Length          float64 `json:"length"`
+	DimensionUnit   string  `json:"dimensionUnit"`
+}
+
+/*
+	Conversion from a unit of measure to the base unit of a material,
+	e.g. with a base unit of joint, 1 ft = 0.025 joint
+*/
+type UnitConversion struct {
+	UnitOfMeasure string  `json:"unitOfMeasure"`
+	Factor        float64 `json:"factor"` // base units per one unit of measure
+}
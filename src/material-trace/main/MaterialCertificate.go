@@ -1,14 +1,38 @@
 package main
 
 type MaterialCertificate struct {
-	ObjectType    string       `json:"docType"`
-	TrackingId    string       `json:"trackingId"`
-	MaterialGroup string       `json:"materialGroup"`
-	Data          []MtrDetails `json:"data"`
-	ReferencedBy  []string     `json:"referencedBy"`
+	ObjectType        string            `json:"docType"`
+	TrackingId        string            `json:"trackingId"`
+	MaterialGroup     string            `json:"materialGroup"`
+	Spec              string            `json:"spec"`                 // spec the heat was made to e.g. API 5L X52
+	HeatNumber        string            `json:"heatNumber,omitempty"` // copied from data for indexed queries
+	Grade             string            `json:"grade,omitempty"`      // copied from data for indexed queries
+	UploadedTimeStamp int64             `json:"uploadedTimeStamp,omitempty"`
+	Data              []MtrDetails      `json:"data"`
+	ReferencedBy      []string          `json:"referencedBy"`
+	Compliance        ComplianceVerdict `json:"compliance"`
+	Document          MtrDocument       `json:"document"`
+	Signature         *MtrSignature     `json:"signature,omitempty"`
+	Revision          int               `json:"revision,omitempty"`
+	Supersedes        int               `json:"supersedes,omitempty"`   // revision this one replaces
+	SupersededBy      int               `json:"supersededBy,omitempty"` // set on archived revisions
+	RevisionReason    string            `json:"revisionReason,omitempty"`
+	RevisedTimeStamp  int64             `json:"revisedTimeStamp,omitempty"`
+}
+
+/*
+	Reference to the original certificate issued by the mill, kept off chain
+*/
+type MtrDocument struct {
+	Sha256    string `json:"sha256"`
+	MediaType string `json:"mediaType"`
+	Uri       string `json:"uri"`
+	FileName  string `json:"fileName"`
+	Size      int64  `json:"size"`
 }
 
 type MtrDetails struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
 }
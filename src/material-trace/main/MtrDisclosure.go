@@ -0,0 +1,51 @@
+package main
+
+import "encoding/json"
+
+/*
+	Defines a material certificate released to the customer, together with where the original is kept
+*/
+type MtrDisclosure struct {
+	ObjectType        string          `json:"docType"`
+	TrackingId        string          `json:"trackingId"`
+	PoId              string          `json:"poId"`
+	SourceCollection  string          `json:"sourceCollection"`
+	Certificate       json.RawMessage `json:"certificate"`                // the original exactly as stored when it was released
+	SourceHash        string          `json:"sourceHash"`                 // on-chain hash of the original when it was released
+	SourceRevision    int             `json:"sourceRevision"`             // revision of the original when it was released
+	LinkedSourceHash  string          `json:"linkedSourceHash,omitempty"` // on-chain hash of the original after later links, which leave its content as released
+	Reason            string          `json:"reason"`
+	ReleasedBy        string          `json:"releasedBy"`
+	ReleasedTimeStamp int64           `json:"releasedTimeStamp"`
+}
+
+/*
+	Defines an audit entry for each release of a material certificate to the customer
+*/
+type MtrDisclosureAudit struct {
+	ObjectType        string `json:"docType"`
+	TrackingId        string `json:"trackingId"`
+	PoId              string `json:"poId"`
+	TxId              string `json:"txId"`
+	ReleasedBy        string `json:"releasedBy"`
+	ReleasedMspId     string `json:"releasedMspId"`
+	Reason            string `json:"reason"`
+	CertificateHash   string `json:"certificateHash"`
+	SourceHash        string `json:"sourceHash"`
+	SourceRevision    int    `json:"sourceRevision"`
+	ReleasedTimeStamp int64  `json:"releasedTimeStamp"`
+}
+
+/*
+	Defines the result of checking a released certificate against the original as it was when released
+*/
+type MtrDisclosureVerification struct {
+	Collection      string `json:"collection"`
+	Key             string `json:"key"`
+	Matches         bool   `json:"matches"`
+	OnChainHash     string `json:"onChainHash"` // hash of the original when it was released
+	PresentedHash   string `json:"presentedHash"`
+	SourceRevision  int    `json:"sourceRevision"`
+	CurrentHash     string `json:"currentHash"`
+	OriginalChanged bool   `json:"originalChanged"` // the original was waived or revised after the release
+}
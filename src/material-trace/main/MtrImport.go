@@ -0,0 +1,26 @@
+package main
+
+/*
+	Defines a certificate in the structured JSON import format, one entry per heat
+*/
+type MtrImportRecord struct {
+	TrackingId     string       `json:"trackingId"`
+	MaterialGroup  string       `json:"materialGroup"`
+	Spec           string       `json:"spec"`
+	HeatNumber     string       `json:"heatNumber"`
+	RevisionReason string       `json:"revisionReason"`
+	Document       MtrDocument  `json:"document"`
+	Signature      string       `json:"signature"` // base64 signature over MtrSignedContent
+	Results        []MtrDetails `json:"results"`
+}
+
+/*
+	Defines the outcome of importing one row or entry of a batch
+*/
+type MtrImportResult struct {
+	Row        int    `json:"row"`
+	TrackingId string `json:"trackingId"`
+	Imported   bool   `json:"imported"`
+	Revision   int    `json:"revision"`
+	Error      string `json:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package main
+
+/*
+	Defines the customer-visible summary of a material certificate linked to an order request.
+	Certificate values stay in the manufacturer MTR collection; the customer gets them through a disclosure.
+*/
+type MtrLink struct {
+	ObjectType      string `json:"docType"`
+	TrackingId      string `json:"trackingId"`
+	PoId            string `json:"poId"`
+	PoNumber        int    `json:"poNumber"`
+	LineNumber      int    `json:"lineNumber"`
+	ItemKey         string `json:"itemKey"`
+	FulfilledBy     string `json:"fulfilledBy"`
+	MaterialGroup   string `json:"materialGroup"`
+	Spec            string `json:"spec"`
+	HeatNumber      string `json:"heatNumber"`
+	Compliance      string `json:"compliance"`
+	DocumentSha256  string `json:"documentSha256"`
+	LinkedTimeStamp int64  `json:"linkedTimeStamp"`
+	Revision        int    `json:"revision"`
+	ReviewRequired  bool   `json:"reviewRequired"` // the certificate was revised after it was linked
+}
@@ -0,0 +1,25 @@
+package main
+
+/*
+	Defines the filters of an MTR query, empty filters are ignored.
+	uploadedFrom and uploadedTo are inclusive epoch milliseconds.
+*/
+type MtrQuery struct {
+	HeatNumber    string `json:"heatNumber"`
+	Grade         string `json:"grade"`
+	MaterialGroup string `json:"materialGroup"`
+	UploadedFrom  int64  `json:"uploadedFrom"`
+	UploadedTo    int64  `json:"uploadedTo"`
+	PoId          string `json:"poId"`
+	PageSize      int    `json:"pageSize"`
+	Bookmark      string `json:"bookmark"`
+}
+
+/*
+	Defines a page of MTR query results; pass bookmark back to fetch the next page
+*/
+type MtrQueryPage struct {
+	Records  []MaterialCertificate `json:"records"`
+	Count    int                   `json:"count"`
+	Bookmark string                `json:"bookmark"`
+}
@@ -0,0 +1,28 @@
+package main
+
+/*
+	Defines the manufacturer signature over the canonical content of a certificate, see MtrSignedContent.
+	The signer certificate is kept so the signature can be checked after the signer's certificate rotates.
+*/
+type MtrSignature struct {
+	Value             string `json:"value"` // base64 encoded signature over the SHA-256 of the signed content
+	Algorithm         string `json:"algorithm"`
+	ContentSha256     string `json:"contentSha256"`
+	SignerMspId       string `json:"signerMspId"`
+	SignerSubject     string `json:"signerSubject"`
+	SignerIssuer      string `json:"signerIssuer"`
+	SignerCertificate string `json:"signerCertificate"` // PEM
+	SignedTimeStamp   int64  `json:"signedTimeStamp"`
+}
+
+/*
+	Defines the certificate content a manufacturer signs, serialized as compact JSON in this field order
+	without HTML escaping, see mtrSignedDigest
+*/
+type MtrSignedContent struct {
+	TrackingId     string       `json:"trackingId"`
+	MaterialGroup  string       `json:"materialGroup"`
+	Spec           string       `json:"spec"`
+	Data           []MtrDetails `json:"data"`
+	DocumentSha256 string       `json:"documentSha256"`
+}
@@ -0,0 +1,29 @@
+package main
+
+/*
+	Defines the fields a material certificate must carry for a material group
+*/
+type MtrTemplate struct {
+	ObjectType            string             `json:"docType"`
+	MaterialGroup         string             `json:"materialGroup"`
+	Fields                []MtrTemplateField `json:"fields"`
+	RequireMtr            bool               `json:"requireMtr"`            // lines may only ship with a linked certificate
+	RequireSignature      bool               `json:"requireSignature"`      // certificates must carry a quality officer signature
+	RequirePassingVerdict bool               `json:"requirePassingVerdict"` // lines may only ship with a passing or waived verdict
+	RequiredAttestations  []string           `json:"requiredAttestations"`  // passing inspector attestations each linked certificate needs
+	ShipmentAttestations  []string           `json:"shipmentAttestations"`  // passing inspector attestations a shipping request needs before pickup
+	UpdatedBy             string             `json:"updatedBy"`
+	UpdatedTimeStamp      int64              `json:"updatedTimeStamp"`
+}
+
+/*
+	A field of an MTR template e.g. yield strength, number, ksi, required, minimum 52
+*/
+type MtrTemplateField struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // string, number, boolean or date
+	Unit     string   `json:"unit"`
+	Required bool     `json:"required"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
@@ -0,0 +1,41 @@
+package main
+
+/*
+	Defines the budget the customer holds for a project
+*/
+type ProjectBudget struct {
+	ObjectType       string  `json:"docType"`
+	ProjectId        string  `json:"projectId"`
+	Description      string  `json:"description"`
+	Amount           float64 `json:"amount"`
+	Currency         string  `json:"currency"`
+	OverrunPolicy    string  `json:"overrunPolicy"` // warn or block
+	UpdatedTimeStamp int64   `json:"updatedTimeStamp"`
+}
+
+/*
+	Defines the spend committed to a project by a single purchase order
+*/
+type ProjectCommitment struct {
+	ObjectType string  `json:"docType"`
+	ProjectId  string  `json:"projectId"`
+	PoId       string  `json:"poId"`
+	PoNumber   int     `json:"poNumber"`
+	Committed  float64 `json:"committed"`
+	Actual     float64 `json:"actual"`
+	Status     string  `json:"status"`
+	TimeStamp  int64   `json:"timeStamp"`
+}
+
+/*
+	Budget, committed and actual spend for a project
+*/
+type ProjectBudgetSummary struct {
+	ProjectId     string  `json:"projectId"`
+	Budget        float64 `json:"budget"`
+	Committed     float64 `json:"committed"`
+	Actual        float64 `json:"actual"`
+	Remaining     float64 `json:"remaining"`
+	Currency      string  `json:"currency"`
+	OverrunPolicy string  `json:"overrunPolicy"`
+}
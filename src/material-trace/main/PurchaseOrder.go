@@ -18,4 +18,6 @@ type PurchaseOrder struct {
 	ExpectedDeliveryDate string     `json:"expectedDeliveryDate"`
 	ClientUserAgent      string     `json:"clientUserAgent"`
 	ProjectId            string     `json:"projectId"`
+	BudgetWarnings       []string   `json:"budgetWarnings,omitempty"` // returned to the client only, never stored
+	VendorWarnings       []string   `json:"vendorWarnings,omitempty"` // returned to the client only, never stored
 }
@@ -0,0 +1,44 @@
+package main
+
+/*
+	Defines a recall of material made under a suspect material certificate or heat
+*/
+type Recall struct {
+	ObjectType        string         `json:"docType"`
+	RecallId          string         `json:"recallId"`
+	TrackingId        string         `json:"trackingId"`
+	HeatNumber        string         `json:"heatNumber"`
+	Reason            string         `json:"reason"`
+	Status            string         `json:"status"`
+	InitiatedBy       string         `json:"initiatedBy"`
+	AffectedItemKeys  []string       `json:"affectedItemKeys"`
+	AffectedShipments []string       `json:"affectedShipments"`
+	AffectedProjects  []string       `json:"affectedProjects"`
+	AffectedOrgs      []string       `json:"affectedOrgs"`
+	HeldItemKeys      []string       `json:"heldItemKeys"`
+	Actions           []RecallAction `json:"actions"`
+	Resolution        string         `json:"resolution"`
+	CreatedTimeStamp  int64          `json:"createdTimeStamp"`
+	ClosedTimeStamp   int64          `json:"closedTimeStamp"`
+}
+
+/*
+	A follow-up action taken by an affected organization, e.g. quarantined, inspected, scrapped
+*/
+type RecallAction struct {
+	Organization string `json:"organization"`
+	Action       string `json:"action"`
+	Comment      string `json:"comment"`
+	TimeStamp    int64  `json:"timeStamp"`
+}
+
+/*
+	Defines a hold that stops a line item from shipping or being verified while a recall is open
+*/
+type RecallHold struct {
+	ObjectType string `json:"docType"`
+	RecallId   string `json:"recallId"`
+	PoId       string `json:"poId"`
+	LineNumber int    `json:"lineNumber"`
+	ItemKey    string `json:"itemKey"`
+}
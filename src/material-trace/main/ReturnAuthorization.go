@@ -0,0 +1,52 @@
+package main
+
+/*
+	Defines a return merchandise authorization (RMA) opened by the customer
+	against delivered line items of a purchase order
+*/
+type ReturnAuthorization struct {
+	ObjectType            string           `json:"docType"`
+	RmaId                 string           `json:"rmaId"`
+	PoId                  string           `json:"poId"`
+	PoNumber              int              `json:"poNumber"`
+	Reason                string           `json:"reason"`
+	Status                string           `json:"status"`
+	RequestedBy           string           `json:"requestedBy"`
+	Manufacturer          string           `json:"manufacturer"`
+	ReturnLines           []ReturnLineItem `json:"returnLines"`
+	ShippingRequestNumber int64            `json:"shippingRequestNumber"`
+	IotTrackingCode       string           `json:"iotTrackingCode"`
+	Resolution            string           `json:"resolution"` // replacement or credit
+	ReplacementOrders     []OrderRequest   `json:"replacementOrders"`
+	CreditNote            CreditNote       `json:"creditNote"`
+	ProgressStatus        []ItemStatus     `json:"progressStatus"`
+	CreatedTimeStamp      int64            `json:"createdTimeStamp"`
+	ClosedTimeStamp       int64            `json:"closedTimeStamp"`
+}
+
+/*
+	A line item and quantity being returned under an RMA
+*/
+type ReturnLineItem struct {
+	LineNumber    int     `json:"lineNumber"`
+	ItemKey       string  `json:"itemKey"`
+	MaterialId    string  `json:"materialId"`
+	Description   string  `json:"description"`
+	Quantity      int     `json:"quantity"`
+	UnitOfMeasure string  `json:"unitOfMeasure"`
+	UnitPrice     float64 `json:"unitPrice"`
+	Reason        string  `json:"reason"`
+	FulfilledBy   string  `json:"fulfilledBy"`
+	// key of the order line added when the RMA is resolved with a replacement
+	ReplacementItemKey string `json:"replacementItemKey"`
+}
+
+/*
+	Credit issued by the distributor to the customer when an RMA is resolved without replacement
+*/
+type CreditNote struct {
+	CreditNoteId string  `json:"creditNoteId"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	TimeStamp    int64   `json:"timeStamp"`
+}
@@ -0,0 +1,28 @@
+package main
+
+/*
+	Defines an individually identified unit, e.g. a pipe joint, under a line item and order request
+*/
+type SerializedUnit struct {
+	ObjectType            string       `json:"docType"`
+	SerialNumber          string       `json:"serialNumber"`
+	PoId                  string       `json:"poId"`
+	PoNumber              int          `json:"poNumber"`
+	LineNumber            int          `json:"lineNumber"`
+	ItemKey               string       `json:"itemKey"`
+	MaterialId            string       `json:"materialId"`
+	FulfilledBy           string       `json:"fulfilledBy"`
+	HeatNumber            string       `json:"heatNumber"`
+	Length                float64      `json:"length"`
+	LengthUnit            string       `json:"lengthUnit"`
+	Weight                float64      `json:"weight"`
+	WeightUnit            string       `json:"weightUnit"`
+	TrackingId            string       `json:"trackingId"` // material certificate the unit was made under
+	Status                string       `json:"status"`
+	ShippingRequestNumber int64        `json:"shippingRequestNumber"`
+	IotTrackingCode       string       `json:"iotTrackingCode"`
+	InstallationLocation  string       `json:"installationLocation"`
+	OnHold                bool         `json:"onHold"`
+	RecallId              string       `json:"recallId"`
+	ProgressStatus        []ItemStatus `json:"progressStatus"`
+}
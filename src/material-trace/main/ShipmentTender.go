@@ -0,0 +1,43 @@
+package main
+
+/*
+	Defines a shipping request tendered to a carrier, stored in that carrier's collection.
+	The carrier accepts, declines or quotes, then the shipper awards the load to one carrier.
+*/
+type ShipmentTender struct {
+	ObjectType            string          `json:"docType"`
+	ShippingRequestNumber int64           `json:"shippingRequestNumber"`
+	PoId                  string          `json:"poId"`
+	PoNumber              int             `json:"poNumber"`
+	Carrier               string          `json:"carrier"` // carrier MSP id
+	TenderedBy            string          `json:"tenderedBy"`
+	Status                string          `json:"status"`
+	Quote                 *FreightQuote   `json:"quote,omitempty"`
+	DeclineReason         string          `json:"declineReason,omitempty"`
+	ShippingRequest       ShippingRequest `json:"shippingRequest"`
+	TenderedTimeStamp     int64           `json:"tenderedTimeStamp"`
+	RespondedTimeStamp    int64           `json:"respondedTimeStamp"`
+	AwardedTimeStamp      int64           `json:"awardedTimeStamp"`
+	ProgressStatus        []ItemStatus    `json:"progressStatus"`
+}
+
+/*
+	Defines the price and transit time a carrier quotes for a tendered load
+*/
+type FreightQuote struct {
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	TransitDays int     `json:"transitDays"`
+	ValidUntil  int64   `json:"validUntil"`
+	Notes       string  `json:"notes"`
+}
+
+/*
+	Defines a carrier's answer to a tender, one of accepted, declined or quoted
+*/
+type TenderResponse struct {
+	Status    string        `json:"status"`
+	Quote     *FreightQuote `json:"quote,omitempty"`
+	Reason    string        `json:"reason"`
+	TimeStamp int64         `json:"timeStamp"`
+}
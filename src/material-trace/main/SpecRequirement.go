@@ -0,0 +1,46 @@
+package main
+
+/*
+	Defines the values a material certificate must meet for an ordered spec e.g. API 5L X52
+*/
+type SpecRequirement struct {
+	ObjectType       string     `json:"docType"`
+	Spec             string     `json:"spec"`
+	Rules            []SpecRule `json:"rules"`
+	UpdatedBy        string     `json:"updatedBy"`
+	UpdatedTimeStamp int64      `json:"updatedTimeStamp"`
+}
+
+/*
+	A single check of a certificate field e.g. yield strength minimum 52 ksi, carbon equivalent maximum 0.43
+*/
+type SpecRule struct {
+	Field    string   `json:"field"`
+	Unit     string   `json:"unit"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Equals   string   `json:"equals"`
+	Required bool     `json:"required"`
+}
+
+/*
+	Outcome of comparing a certificate with a spec
+*/
+type ComplianceVerdict struct {
+	Verdict      string   `json:"verdict"` // pass, fail, waived or pending
+	Spec         string   `json:"spec"`
+	Failures     []string `json:"failures"`
+	WaivedBy     string   `json:"waivedBy"`
+	WaiverReason string   `json:"waiverReason"`
+}
+
+/*
+	Defines the acceptance by the customer or distributor of a certificate that failed its spec
+*/
+type ComplianceWaiver struct {
+	ObjectType string `json:"docType"`
+	TrackingId string `json:"trackingId"`
+	Reason     string `json:"reason"`
+	WaivedBy   string `json:"waivedBy"`
+	TimeStamp  int64  `json:"timeStamp"`
+}
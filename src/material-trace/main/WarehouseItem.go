@@ -0,0 +1,25 @@
+package main
+
+/*
+	Defines a line item held by a warehouse from receipt through put-away and pick
+*/
+type WarehouseItem struct {
+	ObjectType            string       `json:"docType"`
+	Warehouse             string       `json:"warehouse"`
+	PoId                  string       `json:"poId"`
+	PoNumber              int          `json:"poNumber"`
+	LineNumber            int          `json:"lineNumber"`
+	ItemKey               string       `json:"itemKey"`
+	MaterialId            string       `json:"materialId"`
+	Description           string       `json:"description"`
+	Quantity              int          `json:"quantity"`
+	UnitOfMeasure         string       `json:"unitOfMeasure"`
+	ReceivedFrom          string       `json:"receivedFrom"`
+	IotTrackingCode       string       `json:"iotTrackingCode"`
+	BinLocation           string       `json:"binLocation"`
+	StagingLocation       string       `json:"stagingLocation"`
+	ShippingRequestNumber int64        `json:"shippingRequestNumber"`
+	Status                string       `json:"status"`
+	TimeReceived          int64        `json:"timeReceived"`
+	ProgressStatus        []ItemStatus `json:"progressStatus"`
+}
@@ -36,6 +36,10 @@ var organizationMap = map[string]string{
 	"org3msp": "Manufacturer 1",
 	"org4msp": "Manufacturer 2",
 	"org5msp": "Logistics",
+	"org6msp": "Warehouse 1",
+	"org7msp": "Warehouse 2",
+	"org8msp": "Inspector",
+	"org9msp": "Carrier 2",
 }
 
 func main() {
@@ -52,6 +56,8 @@ func main() {
 	// organizationMap["org5msp"] = "Logistics"
 	// organizationMap["org6msp"] = "Warehouse 1"
 	// organizationMap["org7msp"] = "Warehouse 2"
+	// organizationMap["org8msp"] = "Inspector"
+	// organizationMap["org9msp"] = "Carrier 2"
 
 }
 
@@ -64,6 +70,20 @@ const (
 	PRIVATE_COLLECTION_MTR_MFR1                  = "collectionMtrManufacturer1"
 	PRIVATE_COLLECTION_MTR_MFR2                  = "collectionMtrManufacturer2"
 	PRIVATE_COLLECTION_GENERAL_PROGRESS          = "collectionGeneralProgress"
+	PRIVATE_COLLECTION_CUSTOMER_PROJECTS         = "collectionCustomerProjects"
+	PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY     = "collectionDistributorInventory"
+	PRIVATE_COLLECTION_WAREHOUSE1                = "collectionWarehouse1"
+	PRIVATE_COLLECTION_WAREHOUSE2                = "collectionWarehouse2"
+	PRIVATE_COLLECTION_CUSTOMER_MTR_MFR1         = "collectionCustomerMtrManufacturer1"
+	PRIVATE_COLLECTION_CUSTOMER_MTR_MFR2         = "collectionCustomerMtrManufacturer2"
+	PRIVATE_COLLECTION_INSPECTION                = "collectionInspection"
+	PRIVATE_COLLECTION_SHIPPING                  = "collectionShipping"
+	PRIVATE_COLLECTION_CARRIER1                  = "collectionCarrier1"
+	PRIVATE_COLLECTION_CARRIER1_MFR1             = "collectionCarrier1Manufacturer1"
+	PRIVATE_COLLECTION_CARRIER1_MFR2             = "collectionCarrier1Manufacturer2"
+	PRIVATE_COLLECTION_CARRIER2                  = "collectionCarrier2"
+	PRIVATE_COLLECTION_CARRIER2_MFR1             = "collectionCarrier2Manufacturer1"
+	PRIVATE_COLLECTION_CARRIER2_MFR2             = "collectionCarrier2Manufacturer2"
 	DEFAULT_CURRENCY                             = "USD"
 	DEFAULT_MATERIAL_GROUP                       = "pipe"
 	DEFAULT_UNIT_OF_MEASURE                      = "each"
@@ -77,6 +97,26 @@ const (
 	STATUS_DELIVERED                             = "delivered"
 	STATUS_RECEIVED                              = "received"
 	STATUS_VERIFIED                              = "verified"
+	STATUS_RETURN_REQUESTED                      = "return-requested"
+	STATUS_RETURN_AUTHORIZED                     = "return-authorized"
+	STATUS_RETURN_SHIPPING                       = "return-shipping"
+	STATUS_REPLACED                              = "replaced"
+	STATUS_CREDITED                              = "credited"
+	STATUS_WAREHOUSE_RECEIVED                    = "warehouse-received"
+	STATUS_PUT_AWAY                              = "put-away"
+	STATUS_STAGED                                = "staged"
+	STATUS_INSTALLED                             = "installed"
+	STATUS_ON_HOLD                               = "on-hold"
+	STATUS_HOLD_RELEASED                         = "hold-released"
+	STATUS_CLOSED                                = "closed"
+	STATUS_MTR_LINKED                            = "mtr-linked"
+	STATUS_MTR_REVISED                           = "mtr-revised"
+	STATUS_MTR_REVISION_REVIEWED                 = "mtr-revision-reviewed"
+	STATUS_TENDERED                              = "tendered"
+	STATUS_QUOTED                                = "quoted"
+	STATUS_DECLINED                              = "declined"
+	STATUS_AWARDED                               = "awarded"
+	STATUS_NOT_AWARDED                           = "not-awarded"
 )
 
 // handleValidateOrderRequest
@@ -174,11 +214,11 @@ func (s *SmartContract) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 		}
 		return s.handleValidateOrderRequest(stub, args)
 	case "logistics-order-requests":
-		validMsps := "org5msp"
+		validMsps := "org5msp|org9msp"
 		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
 			resMsg := ResponseMessage{}
 			resMsg.Success = false
-			resMsg.ErrorMessage = "Unexpected organization, expecting logistics but got " + organizationMap[str.ToLower(si.Mspid)]
+			resMsg.ErrorMessage = "Unexpected organization, expecting a carrier but got " + organizationMap[str.ToLower(si.Mspid)]
 			msgBytes, _ := json.Marshal(resMsg)
 			return shim.Error(string(msgBytes))
 		}
@@ -196,10 +236,10 @@ func (s *SmartContract) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 		}
 		return s.queryAllCustomer(stub)
 	case "acceptandshiptocustomer":
-		// shipping organization only
-		validMsps := "org5msp"
+		// carrier organizations only
+		validMsps := "org5msp|org9msp"
 		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
-			return shim.Error("Unexpected organization, only the shipper organization can ship a package")
+			return shim.Error("Unexpected organization, only a carrier organization can ship a package")
 		}
 		return s.logisticsAcceptAndShipsToCustomer(stub, args)
 	case "onlogisticsacceptance":
@@ -214,6 +254,66 @@ func (s *SmartContract) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 			return shim.Error("Unexpected organization, expecting the distributor")
 		}
 		return s.advanceInTransitItem(stub, args)
+	case "shipping-request":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only members of the shipping collection can read shipping requests")
+		}
+		return s.queryShippingRequest(stub, args)
+	case "shipping-requests":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only members of the shipping collection can read shipping requests")
+		}
+		return s.queryShippingRequestsForPo(stub, args)
+	case "migrate-shipping-requests":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, expecting the distributor")
+		}
+		return s.migrateShippingRequests(stub, args)
+	case "consolidate-load":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the distributor or a manufacturer can consolidate a load")
+		}
+		return s.consolidateLoad(stub, args)
+	case "consolidated-load":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only members of the shipping collection can read loads")
+		}
+		return s.queryConsolidatedLoad(stub, args)
+	case "tender-shipment":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the distributor or a manufacturer can tender a shipment")
+		}
+		return s.tenderShipment(stub, args)
+	case "respond-to-tender":
+		validMsps := "org5msp|org9msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only a carrier can respond to a tender")
+		}
+		return s.respondToTender(stub, args)
+	case "award-shipment":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the distributor or a manufacturer can award a shipment")
+		}
+		return s.awardShipment(stub, args)
+	case "shipment-tenders":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, expecting the distributor or a manufacturer")
+		}
+		return s.queryShipmentTenders(stub, args)
+	case "sync-carrier-tenders":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, expecting the distributor")
+		}
+		return s.syncCarrierTenders(stub, args)
 	case "shippeditemslist":
 		return s.fetchAllShippedItems(stub, args)
 	case "incomingiot":
@@ -232,6 +332,18 @@ func (s *SmartContract) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 			return shim.Error("Unexpected organization, only manufacturer can upload mtr")
 		}
 		return s.addMaterialCertificate(stub, args)
+	case "verify-mtr-signature":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.verifyMtrSignature(stub, args)
+	case "import-mtrs":
+		validMsps := "org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only manufacturer can upload mtr")
+		}
+		return s.importMaterialCertificates(stub, args)
 	case "onmanufacturershipmentnotification":
 		validMsps := "org2msp"
 		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
@@ -253,6 +365,292 @@ func (s *SmartContract) Invoke(stub shim.ChaincodeStubInterface) sc.Response {
 			return shim.Error("Unexpected organization, expecting org2,org3, or org4")
 		}
 		return s.queryMtrItems(stub, args)
+	case "mtr-query":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, expecting org2,org3, or org4")
+		}
+		return s.queryMtrs(stub, args)
+	case "open-rma":
+		validMsps := "org1msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer org can open a return authorization")
+		}
+		return s.openReturnAuthorization(stub, args)
+	case "route-rma":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected.")
+		}
+		return s.routeReturnAuthorization(stub, args)
+	case "request-return-shipping":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected.")
+		}
+		return s.requestReturnShipping(stub, args)
+	case "resolve-rma":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected.")
+		}
+		return s.resolveReturnAuthorization(stub, args)
+	case "rma-list":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, expecting org1, org2, org3, or org4")
+		}
+		return s.queryReturnAuthorizations(stub, args)
+	case "margin-report":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the distributor can view the margin report")
+		}
+		return s.queryMarginReport(stub, args)
+	case "verify-private-data":
+		return s.verifyPrivateData(stub, args)
+	case "private-data-hash":
+		return s.queryPrivateDataHash(stub, args)
+	case "set-project-budget":
+		validMsps := "org1msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer org can maintain project budgets")
+		}
+		return s.setProjectBudget(stub, args)
+	case "project-budgets":
+		validMsps := "org1msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, utility / customer expected")
+		}
+		return s.queryProjectBudgets(stub, args)
+	case "inventory-receipt":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected")
+		}
+		return s.receiveInventory(stub, args)
+	case "inventory-adjustment":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected")
+		}
+		return s.adjustInventory(stub, args)
+	case "inventory-on-hand":
+		validMsps := "org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor expected")
+		}
+		return s.queryInventoryOnHand(stub, args)
+	case "set-material":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer or distributor can maintain the material catalog")
+		}
+		return s.setMaterial(stub, args)
+	case "material-catalog":
+		return s.queryMaterials(stub, args)
+	case "convert-uom":
+		return s.convertUnits(stub, args)
+	case "set-approved-vendor":
+		validMsps := "org1msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer org can maintain the approved vendor list")
+		}
+		return s.setApprovedVendor(stub, args)
+	case "approved-vendors":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, customer or distributor expected")
+		}
+		return s.queryApprovedVendors(stub, args)
+	case "eligible-manufacturers":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, customer or distributor expected")
+		}
+		return s.queryEligibleManufacturers(stub, args)
+	case "warehouse-receive":
+		validMsps := "org6msp|org7msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, warehouse expected")
+		}
+		return s.warehouseReceive(stub, args)
+	case "warehouse-putaway":
+		validMsps := "org6msp|org7msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, warehouse expected")
+		}
+		return s.warehousePutAway(stub, args)
+	case "warehouse-pick":
+		validMsps := "org6msp|org7msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, warehouse expected")
+		}
+		return s.warehousePick(stub, args)
+	case "warehouse-items":
+		validMsps := "org2msp|org5msp|org6msp|org7msp|org9msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor, carrier or warehouse expected")
+		}
+		return s.queryWarehouseItems(stub, args)
+	case "register-units":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor or manufacturer expected")
+		}
+		return s.registerSerializedUnits(stub, args)
+	case "install-units":
+		validMsps := "org1msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, utility / customer expected")
+		}
+		return s.installSerializedUnits(stub, args)
+	case "serialized-units":
+		validMsps := "org1msp|org2msp|org3msp|org4msp|org6msp|org7msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, logistics has no access to serialized units")
+		}
+		return s.querySerializedUnits(stub, args)
+	case "genealogy-forward":
+		return s.traceGenealogy(stub, args, GENEALOGY_FORWARD)
+	case "genealogy-backward":
+		return s.traceGenealogy(stub, args, GENEALOGY_BACKWARD)
+	case "open-recall":
+		validMsps := "org1msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only a manufacturer or the customer can open a recall")
+		}
+		return s.openRecall(stub, args)
+	case "recall-action":
+		validMsps := "org1msp|org2msp|org3msp|org4msp|org5msp|org9msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.recordRecallAction(stub, args)
+	case "close-recall":
+		validMsps := "org1msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only a manufacturer or the customer can close a recall")
+		}
+		return s.closeRecall(stub, args)
+	case "set-mtr-template":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer or distributor can maintain mtr templates")
+		}
+		return s.setMtrTemplate(stub, args)
+	case "mtr-templates":
+		return s.queryMtrTemplates(stub, args)
+	case "set-spec-requirement":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer or distributor can maintain spec requirements")
+		}
+		return s.setSpecRequirement(stub, args)
+	case "spec-requirements":
+		return s.querySpecRequirements(stub, args)
+	case "waive-mtr-compliance":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, only the customer or distributor can waive compliance")
+		}
+		return s.waiveMtrCompliance(stub, args)
+	case "disclose-mtr":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor or manufacturer expected")
+		}
+		return s.discloseMtr(stub, args)
+	case "disclosed-mtrs":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryDisclosedMtrs(stub, args)
+	case "mtr-disclosure-audit":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryMtrDisclosureAudit(stub, args)
+	case "verify-mtr-disclosure":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.verifyMtrDisclosure(stub, args)
+	case "assign-inspection":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, customer or distributor expected")
+		}
+		return s.assignInspection(stub, args)
+	case "record-attestation":
+		validMsps := "org8msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, inspector expected")
+		}
+		return s.recordAttestation(stub, args)
+	case "share-attestation-progress":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, customer or distributor expected")
+		}
+		return s.shareAttestationProgress(stub, args)
+	case "inspection-assignments":
+		validMsps := "org1msp|org2msp|org8msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryInspectionAssignments(stub, args)
+	case "attestations":
+		validMsps := "org1msp|org2msp|org3msp|org4msp|org5msp|org8msp|org9msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryAttestations(stub, args)
+	case "mtr-revisions":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor or manufacturer expected")
+		}
+		return s.queryMtrRevisions(stub, args)
+	case "review-mtr-revision":
+		validMsps := "org1msp|org2msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, customer or distributor expected")
+		}
+		return s.reviewMtrRevision(stub, args)
+	case "link-mtr":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor or manufacturer expected")
+		}
+		return s.linkMtr(stub, args)
+	case "mtr-links":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryMtrLinks(stub, args)
+	case "verify-mtr-document":
+		validMsps := "org1msp|org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.verifyMtrDocument(stub, args)
+	case "mtr-compliance":
+		validMsps := "org2msp|org3msp|org4msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization, distributor or manufacturer expected")
+		}
+		return s.queryMtrCompliance(stub, args)
+	case "recalls":
+		validMsps := "org1msp|org2msp|org3msp|org4msp|org5msp|org9msp"
+		if !str.Contains(str.ToLower(validMsps), str.ToLower(si.Mspid)) {
+			return shim.Error("Unexpected organization - " + si.Mspid)
+		}
+		return s.queryRecalls(stub, args)
 	default:
 		logger.Warningf("Invoke('%s') invalid!", function)
 		return Error(http.StatusNotImplemented, "Invalid method! Valid methods are 'createpo|'!")
@@ -277,6 +675,32 @@ func generateItemKey(poId string, lineItem LineItem) string {
 	return str.Join(itemProps, "|")
 }
 
+/*
+* Given a manufacturer name return the private collection shared between the distributor and that manufacturer
+ */
+func manufacturerCollection(manufacturer string) string {
+	switch str.ToLower(manufacturer) {
+	case str.ToLower(organizationMap["org3msp"]):
+		return PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER1
+	case str.ToLower(organizationMap["org4msp"]):
+		return PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER2
+	}
+	return ""
+}
+
+/*
+* Given a manufacturer MSP id return the private collection holding its material certificates
+ */
+func mtrCollection(mspId string) string {
+	switch mspId {
+	case "org3msp": // "manufacturer 1"
+		return PRIVATE_COLLECTION_MTR_MFR1
+	case "org4msp": // "manufacturer 2"
+		return PRIVATE_COLLECTION_MTR_MFR2
+	}
+	return ""
+}
+
 func (s *SmartContract) addMaterialCertificate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
 	materialCert := MaterialCertificate{}
 	json.Unmarshal([]byte(args[0]), &materialCert)
@@ -304,18 +728,63 @@ func (s *SmartContract) addMaterialCertificate(stub shim.ChaincodeStubInterface,
 		privateCollection = PRIVATE_COLLECTION_MTR_MFR2
 		materialCert.ObjectType = PRIVATE_COLLECTION_MTR_MFR2
 	}
-	mtrBytes, err := json.Marshal(materialCert)
-	err = stub.PutPrivateData(privateCollection, materialCert.TrackingId, mtrBytes)
+	revisedItemKeys := make(map[string][]string)
+	materialCert, err := storeMaterialCertificate(stub, privateCollection, materialCert, TRANSIENT_MTR_DOCUMENT, revisedItemKeys)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	commitMtrRevisionFlags(stub, revisedItemKeys)
+	mtrBytes, _ := json.Marshal(materialCert)
 	return shim.Success(mtrBytes)
 }
 
+/*
+	Method: storeMaterialCertificate
+	Validates a certificate against its template, evaluates compliance, anchors the original document
+	found in the transient map under documentKey and stores it, archiving any prior revision.
+	Lines linked to a revised certificate are collected in revisedItemKeys for commitMtrRevisionFlags.
+*/
+func storeMaterialCertificate(stub shim.ChaincodeStubInterface, privateCollection string, materialCert MaterialCertificate, documentKey string, revisedItemKeys map[string][]string) (MaterialCertificate, error) {
+	if err := validateMaterialCertificate(stub, materialCert); err != nil {
+		return materialCert, err
+	}
+	// an existing certificate is never overwritten, the stored one is archived as a prior revision
+	previousCert, revised, err := reviseMaterialCertificate(stub, privateCollection, &materialCert)
+	if err != nil {
+		return materialCert, err
+	}
+	materialCert.Compliance = evaluateCompliance(stub, materialCert, materialCert.Spec)
+	materialCert.HeatNumber = heatNumberFromMtr(materialCert)
+	materialCert.Grade = gradeFromMtr(materialCert)
+	materialCert.UploadedTimeStamp = txTimeStamp(stub)
+	if err := anchorMtrDocument(stub, &materialCert, documentKey); err != nil {
+		return materialCert, err
+	}
+	if revised && materialCert.Document.Sha256 == "" {
+		materialCert.Document = previousCert.Document
+	}
+	// the signature covers the document hash, so it is checked once the document is anchored
+	if err := signMaterialCertificate(stub, &materialCert); err != nil {
+		return materialCert, err
+	}
+	mtrBytes, _ := json.Marshal(materialCert)
+	err = stub.PutPrivateData(privateCollection, materialCert.TrackingId, mtrBytes)
+	if err != nil {
+		return materialCert, err
+	}
+	if heatNumber := heatNumberFromMtr(materialCert); heatNumber != "" {
+		recordGenealogyEdge(stub, privateCollection, GenealogyEdge{FromType: GENEALOGY_NODE_HEAT, FromId: heatNumber, ToType: GENEALOGY_NODE_MTR, ToId: materialCert.TrackingId})
+	}
+	if revised {
+		flagMtrRevision(stub, materialCert, revisedItemKeys)
+	}
+	return materialCert, nil
+}
+
 func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, args []string) sc.Response {
 
-	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting four arguments. 1. PoId 2. lineItems 3. shippingRequestNumber 4. progress status 5. logistics initial progress status")
+	if len(args) != 5 && len(args) != 6 {
+		return shim.Error("Incorrect number of arguments. Expecting five arguments. 1. PoId 2. lineItems 3. shippingRequestNumber 4. progress status 5. logistics initial progress status 6. ship from (optional)")
 	}
 	lineItemsToShip := []LineItem{}
 	err := json.Unmarshal([]byte(args[1]), &lineItemsToShip)
@@ -342,6 +811,13 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 	if err != nil {
 		return shim.Error("Unable to parse progress status data provided - " + args[4])
 	}
+	shipFrom := Company{}
+	if len(args) == 6 && args[5] != "" {
+		err = json.Unmarshal([]byte(args[5]), &shipFrom)
+		if err != nil {
+			return shim.Error("Unable to parse ship from location provided - " + args[5])
+		}
+	}
 
 	// iotTrackingCode := args[1]
 	privateCollection := ""
@@ -364,29 +840,30 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 	if err1 != nil {
 		logger.Info("Unable to get " + privateCollection + " data for PO: " + poId)
 	} else {
-		shippingPd := ShippingPrivateDetails{}
-		shippingPrivateDataResponse, _ := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
-		hasExistingData := false
-		if shippingPrivateDataResponse != nil {
-			json.Unmarshal(shippingPrivateDataResponse, &shippingPd)
-			hasExistingData = true
-		} else {
-			shippingPd.LineItems = make([]ShippingLineItem, 1)
-		}
-		shippingPd.ObjectType = PRIVATE_COLLECTION_LOGISTICS
-		shippingPd.PoId = poId
 		// ShippingRequest
+		shippingRequest, err := startShippingRequest(stub, poId, shippingRequestNumber, shippingRequestedBy, shipFrom)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 		sharedItemsMap := make(map[int]LineItem)
 		if poPrivateDataResponse != nil && isDistributor {
 			itemPrivateData := LineItemPrivateDetails{}
 			json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
 			shippingItemCount := 0
+			inventoryCache := make(map[string]*InventoryRecord)
 			for i, eachItem := range itemPrivateData.LineItems {
 				// lineItem := lineitemToShipMap[eachItem.LineNumber]
 				lineItem := lineitemToShipMap[eachItem.ItemKey]
 				if eachItem.LineNumber != lineItem.LineNumber {
 					continue
 				}
+				mtrs, compliance, err := checkLineCertificates(stub, poId, eachItem.ItemKey, shippingRequestedBy, eachItem.LineNumber, eachItem.MaterialId, eachItem.MaterialGroup, lineItem.MaterialCertificate)
+				if err != nil {
+					return shim.Error(err.Error())
+				}
+				lineItem.MaterialCertificate = mtrs
+				lineItem.Compliance = compliance
+				itemPrivateData.LineItems[i].Compliance = compliance
 				itemPrivateData.LineItems[i].MaterialCertificate = lineItem.MaterialCertificate
 				itemPrivateData.LineItems[i].TimeShipped = lineItem.TimeShipped
 				itemPrivateData.LineItems[i].IotTrackingCode = lineItem.IotTrackingCode
@@ -405,8 +882,13 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 					eachItem.OrderRequests[j].Status = STATUS_SHIPPED // "readyforshipment"
 					eachItem.OrderRequests[j].IotTrackingCode = lineItem.IotTrackingCode
 					eachItem.OrderRequests[j].TimeShipped = lineItem.TimeShipped
-					// shippingPd = fillShippingLineItems(poId, lineItem.PoNumber, lineItem.LineNumber, lineItem.ShipToLocation, shippingRequestedBy, hasExistingData, shippingItemCount, shippingPd, lineItem)
-					shippingPd = fillShippingLineItems(poId, shippingRequestNumber, lineItem, shippingRequestedBy, hasExistingData, shippingItemCount, shippingPd, logisticsInitialStatus, progressStatus)
+					// stock reserved at acceptance leaves the distributor inventory
+					if orderItem.FulfilledBy == organizationMap["org2msp"] {
+						issueReservedInventory(stub, inventoryCache, eachItem.MaterialId, eachItem.ItemKey, progressStatus.TimeStamp)
+						shipSerializedUnits(stub, poId, eachItem.ItemKey, orderItem.FulfilledBy, shippingRequestNumber, lineItem.IotTrackingCode, progressStatus)
+						recordShipmentGenealogy(stub, poId, eachItem.ItemKey, orderItem.FulfilledBy, shippingRequestNumber, lineItem.IotTrackingCode, progressStatus.TimeStamp)
+					}
+					addShippingLineItem(&shippingRequest, fillShippingLineItems(stub, poId, shippingRequestNumber, lineItem, shippingRequestedBy, logisticsInitialStatus, progressStatus))
 					shippingItemCount += 1
 				}
 			}
@@ -422,7 +904,12 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 			if err != nil {
 				return shim.Error(err.Error())
 			}
-			commitShippingPrivateData(stub, poId, shippingPd)
+			if err := commitShippingRequest(stub, shippingRequest); err != nil {
+				return shim.Error(err.Error())
+			}
+			if err := commitInventory(stub, inventoryCache); err != nil {
+				return shim.Error(err.Error())
+			}
 			// // update distributor
 			updateDistributorFulfilledLineItems(stub, poId, STATUS_SHIPPED, lineitemToShipMap, progressStatus)
 			return shim.Success(pdLineItemBytes)
@@ -438,6 +925,13 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 				if priceInfo.LineNumber != lineItem.LineNumber {
 					continue
 				}
+				mtrs, compliance, err := checkLineCertificates(stub, poId, priceInfo.ItemKey, shippingRequestedBy, priceInfo.LineNumber, priceInfo.MaterialId, priceInfo.MaterialGroup, lineItem.MaterialCertificate)
+				if err != nil {
+					return shim.Error(err.Error())
+				}
+				lineItem.MaterialCertificate = mtrs
+				lineItem.Compliance = compliance
+				itemPrivateData.LineItems[i].Compliance = compliance
 				itemPrivateData.LineItems[i].Status = progressStatus.Status // STATUS_SHIPPED
 				itemPrivateData.LineItems[i].ProgressStatus = append(itemPrivateData.LineItems[i].ProgressStatus, progressStatus)
 				itemPrivateData.LineItems[i].IotTrackingCode = lineItem.IotTrackingCode
@@ -445,8 +939,9 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 				itemPrivateData.LineItems[i].TimeShipped = lineItem.TimeShipped
 				lineItem.ShippingRequestNumber = shippingRequestNumber
 				sharedItemsMap[lineItem.LineNumber] = lineItem
-				// shippingPd = fillShippingLineItems(poId, priceInfo.PoNumber, priceInfo.LineNumber, priceInfo.ShipToLocation, shippingRequestedBy, hasExistingData, shippingItemCount, shippingPd, lineItem)
-				shippingPd = fillShippingLineItems(poId, shippingRequestNumber, lineItem, shippingRequestedBy, hasExistingData, shippingItemCount, shippingPd, logisticsInitialStatus, progressStatus)
+				shipSerializedUnits(stub, poId, priceInfo.ItemKey, shippingRequestedBy, shippingRequestNumber, lineItem.IotTrackingCode, progressStatus)
+				recordShipmentGenealogy(stub, poId, priceInfo.ItemKey, shippingRequestedBy, shippingRequestNumber, lineItem.IotTrackingCode, progressStatus.TimeStamp)
+				addShippingLineItem(&shippingRequest, fillShippingLineItems(stub, poId, shippingRequestNumber, lineItem, shippingRequestedBy, logisticsInitialStatus, progressStatus))
 				shippingItemCount += 1
 			}
 
@@ -461,7 +956,9 @@ func (s *SmartContract) notifyShipToCustomer(stub shim.ChaincodeStubInterface, a
 			if err != nil {
 				return shim.Error(err.Error())
 			}
-			commitShippingPrivateData(stub, poId, shippingPd)
+			if err := commitShippingRequest(stub, shippingRequest); err != nil {
+				return shim.Error(err.Error())
+			}
 			return shim.Success(pdLineItemBytes)
 		}
 	}
@@ -486,6 +983,65 @@ func (s *SmartContract) incomingIOT2(stub shim.ChaincodeStubInterface, args []st
 	// 	TimeStamp: iotInput.Timestamp,
 	// }
 
+	// readings of a consolidated load's tracker are fanned out to every PO on the load.
+	// Only members of the shipping collection can see loads, for everyone else it is a PO.
+	load := ConsolidatedLoad{}
+	isLoad := false
+	if str.Contains("org1msp|org2msp|org3msp|org4msp", currentMspId) {
+		var err error
+		load, isLoad, err = getConsolidatedLoad(stub, poId)
+		if err != nil {
+			logger.Info("unable to read load " + poId + ", recording it as a PO: " + err.Error())
+			isLoad = false
+		}
+	}
+	if isLoad {
+		if iotInput.TrackingCode != "" && iotInput.TrackingCode != load.IotTrackingCode {
+			return shim.Error("Tracking code " + iotInput.TrackingCode + " is not the tracker of load " + load.LoadId)
+		}
+		deliveries := make([]ItemDeliveryEvent, 0)
+		updatedCount := 0
+		for _, target := range loadTrackingTargets(load) {
+			// the load's tracker stands in for the tracking code the PO's lines are recorded under
+			poIotInput := iotInput
+			poIotInput.TrackingCode = target.IotTrackingCode
+			delivery, err := updateIncomingIOT(stub, target.PoId, poIotInput, itemStatus, messageKey)
+			if err != nil {
+				logger.Info("load " + load.LoadId + ": " + err.Error())
+				continue
+			}
+			updatedCount += 1
+			if delivery != nil {
+				deliveries = append(deliveries, *delivery)
+			}
+		}
+		if updatedCount == 0 {
+			return shim.Error("Unable to update IOT data for load " + load.LoadId + " for " + currentMspId)
+		}
+		if len(deliveries) > 0 {
+			setItemDeliveryEvent(stub, ItemDeliveryEvent{Type: messageKey, Status: STATUS_DELIVERED, LoadId: load.LoadId, TrackingCode: load.IotTrackingCode, ProgressStatus: itemStatus, Deliveries: deliveries})
+		}
+		loadIotBytes, _ := json.Marshal(iotInput)
+		return shim.Success(loadIotBytes)
+	}
+	delivery, err := updateIncomingIOT(stub, poId, iotInput, itemStatus, messageKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if delivery != nil {
+		setItemDeliveryEvent(stub, *delivery)
+	}
+	// return Success(http.StatusOK, "Sucessfully recorded IOT data", nil)
+	poArrayAsBytes, _ := json.Marshal(iotInput)
+	return shim.Success(poArrayAsBytes)
+
+}
+
+/*
+	Method: updateIncomingIOT
+	Records IOT data on the lines of a PO the calling organization tracks
+*/
+func updateIncomingIOT(stub shim.ChaincodeStubInterface, poId string, iotInput IotProperty, itemStatus ItemStatus, messageKey string) (*ItemDeliveryEvent, error) {
 	privateCollection := ""
 	isDistributor := false
 	switch currentMspId {
@@ -498,22 +1054,20 @@ func (s *SmartContract) incomingIOT2(stub shim.ChaincodeStubInterface, args []st
 		isDistributor = true
 	}
 	ok := false
+	var delivery *ItemDeliveryEvent
 	if !isDistributor {
-		ok = updateManufacturerIncomingIOT(stub, privateCollection, poId, iotInput, itemStatus, messageKey)
+		ok, delivery = updateManufacturerIncomingIOT(stub, privateCollection, poId, iotInput, itemStatus, messageKey)
 		if !ok {
-			return shim.Error("Unable to update Manufacturer IOT data for " + currentMspId)
+			return nil, fmt.Errorf("Unable to update Manufacturer IOT data for %s", currentMspId)
 		}
 		// ok = updateDistributorIncomingIOT(stub, poId, iotInput)
 	} else {
 		ok = updateDistributorIncomingIOT(stub, poId, iotInput, itemStatus, messageKey)
 	}
 	if !ok {
-		return shim.Error("Unable to update IOT data for " + currentMspId)
+		return nil, fmt.Errorf("Unable to update IOT data for %s", currentMspId)
 	}
-	// return Success(http.StatusOK, "Sucessfully recorded IOT data", nil)
-	poArrayAsBytes, _ := json.Marshal(iotInput)
-	return shim.Success(poArrayAsBytes)
-
+	return delivery, nil
 }
 
 /*
@@ -557,3 +1111,15 @@ func Error(rc int32, doc string) sc.Response {
 		Message: doc,
 	}
 }
+
+/*
+	Method: txTimeStamp
+	Transaction time in epoch milliseconds, identical on every endorsing peer
+*/
+func txTimeStamp(stub shim.ChaincodeStubInterface) int64 {
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil || timestamp == nil {
+		return 0
+	}
+	return timestamp.Seconds*1000 + int64(timestamp.Nanos)/1000000
+}
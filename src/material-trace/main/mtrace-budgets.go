@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_PROJECT_BUDGET     = "projectBudget"
+	DOC_TYPE_PROJECT_COMMITMENT = "projectCommitment"
+	BUDGET_POLICY_WARN          = "warn"
+	BUDGET_POLICY_BLOCK         = "block"
+	// the distributor records commitments on acceptance, so they are kept with the customer's line items
+	// while budgets stay in the customer only project collection
+	PROJECT_COMMITMENT_COLLECTION = PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR
+)
+
+/*
+	Method: setProjectBudget
+	Executed when the customer creates or updates the budget of a project
+*/
+func (s *SmartContract) setProjectBudget(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. project budget")
+	}
+	budget := ProjectBudget{}
+	err := json.Unmarshal([]byte(args[0]), &budget)
+	if err != nil {
+		return shim.Error("Unable to parse project budget data provided - " + args[0])
+	}
+	if budget.ProjectId == "" {
+		return shim.Error("projectId is required.")
+	}
+	if budget.Amount < 0 {
+		return shim.Error("Budget amount cannot be negative.")
+	}
+	budget.OverrunPolicy = str.ToLower(budget.OverrunPolicy)
+	if budget.OverrunPolicy == "" {
+		budget.OverrunPolicy = BUDGET_POLICY_WARN
+	}
+	if budget.OverrunPolicy != BUDGET_POLICY_WARN && budget.OverrunPolicy != BUDGET_POLICY_BLOCK {
+		return shim.Error("Expecting warn or block for overrunPolicy. Found: " + budget.OverrunPolicy)
+	}
+	if budget.Currency == "" {
+		budget.Currency = DEFAULT_CURRENCY
+	}
+	budget.ObjectType = DOC_TYPE_PROJECT_BUDGET
+	budgetKey, err := stub.CreateCompositeKey(DOC_TYPE_PROJECT_BUDGET, []string{budget.ProjectId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	budgetBytes, _ := json.Marshal(budget)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_CUSTOMER_PROJECTS, budgetKey, budgetBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(budgetBytes)
+}
+
+/*
+	Method: queryProjectBudgets
+	Returns budget, committed and actual spend for every project with a budget
+*/
+func (s *SmartContract) queryProjectBudgets(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_CUSTOMER_PROJECTS, DOC_TYPE_PROJECT_BUDGET, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	summaries := make([]ProjectBudgetSummary, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		budget := ProjectBudget{}
+		json.Unmarshal(queryResponse.Value, &budget)
+		summary, _ := getProjectBudgetSummary(stub, budget.ProjectId)
+		summaries = append(summaries, summary)
+	}
+	summaryBytes, _ := json.Marshal(summaries)
+	return shim.Success(summaryBytes)
+}
+
+/*
+	Method: getProjectBudgetSummary
+	Adds up the commitments recorded against a project. Returns false when the project has
+	no budget or the current org cannot read the customer project collection.
+*/
+func getProjectBudgetSummary(stub shim.ChaincodeStubInterface, projectId string) (ProjectBudgetSummary, bool) {
+	summary := ProjectBudgetSummary{ProjectId: projectId}
+	budgetKey, err := stub.CreateCompositeKey(DOC_TYPE_PROJECT_BUDGET, []string{projectId})
+	if err != nil {
+		return summary, false
+	}
+	budgetResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_PROJECTS, budgetKey)
+	if err != nil || budgetResponse == nil {
+		return summary, false
+	}
+	budget := ProjectBudget{}
+	json.Unmarshal(budgetResponse, &budget)
+	summary.Budget = budget.Amount
+	summary.Currency = budget.Currency
+	summary.OverrunPolicy = budget.OverrunPolicy
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PROJECT_COMMITMENT_COLLECTION, DOC_TYPE_PROJECT_COMMITMENT, []string{projectId})
+	if err != nil {
+		logger.Infof("Unable to get commitments for project: %s ", projectId)
+		return summary, true
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			break
+		}
+		commitment := ProjectCommitment{}
+		json.Unmarshal(queryResponse.Value, &commitment)
+		summary.Committed += commitment.Committed
+		summary.Actual += commitment.Actual
+	}
+	summary.Remaining = summary.Budget - summary.Committed
+	return summary, true
+}
+
+/*
+	Method: checkProjectBudgets
+	Compares the spend a new PO would commit per project against the remaining budget.
+	Returns an error for projects whose budget blocks overruns and warnings for the others.
+*/
+func checkProjectBudgets(stub shim.ChaincodeStubInterface, projectTotals map[string]float64) ([]string, error) {
+	warnings := make([]string, 0)
+	for _, projectId := range sortedProjectIds(projectTotals) {
+		summary, found := getProjectBudgetSummary(stub, projectId)
+		if !found || projectTotals[projectId] <= summary.Remaining {
+			continue
+		}
+		msg := fmt.Sprintf("purchase order total %.2f exceeds the remaining budget %.2f of project %s", projectTotals[projectId], summary.Remaining, projectId)
+		if summary.OverrunPolicy == BUDGET_POLICY_BLOCK {
+			return warnings, fmt.Errorf("%s", msg)
+		}
+		warnings = append(warnings, msg)
+	}
+	return warnings, nil
+}
+
+/*
+	Method: recordProjectCommitments
+	Records the spend a PO commits to each project. Each PO has its own commitment record,
+	so the distributor can record acceptance without reading the customer's budgets.
+*/
+func recordProjectCommitments(stub shim.ChaincodeStubInterface, poId string, poNumber int, projectTotals map[string]float64, status string, timeStamp int64) error {
+	for _, projectId := range sortedProjectIds(projectTotals) {
+		commitmentKey, err := stub.CreateCompositeKey(DOC_TYPE_PROJECT_COMMITMENT, []string{projectId, poId})
+		if err != nil {
+			return err
+		}
+		commitment := ProjectCommitment{
+			ObjectType: DOC_TYPE_PROJECT_COMMITMENT,
+			ProjectId:  projectId,
+			PoId:       poId,
+			PoNumber:   poNumber,
+			Committed:  projectTotals[projectId],
+			Status:     status,
+			TimeStamp:  timeStamp,
+		}
+		commitmentBytes, _ := json.Marshal(commitment)
+		err = stub.PutPrivateData(PROJECT_COMMITMENT_COLLECTION, commitmentKey, commitmentBytes)
+		if err != nil {
+			return fmt.Errorf("unable to record the commitment of po %s to project %s: %s", poId, projectId, err.Error())
+		}
+	}
+	return nil
+}
+
+/*
+	Method: recordProjectActuals
+	Adds the value of verified line items to the actual spend of their projects
+*/
+func recordProjectActuals(stub shim.ChaincodeStubInterface, poId string, poProjectId string, verifiedLineItems []LineItem) error {
+	projectActuals := projectTotalsForLineItems(poProjectId, verifiedLineItems)
+	for _, projectId := range sortedProjectIds(projectActuals) {
+		commitmentKey, err := stub.CreateCompositeKey(DOC_TYPE_PROJECT_COMMITMENT, []string{projectId, poId})
+		if err != nil {
+			return err
+		}
+		commitmentResponse, err := stub.GetPrivateData(PROJECT_COMMITMENT_COLLECTION, commitmentKey)
+		if err != nil {
+			return err
+		}
+		if commitmentResponse == nil {
+			logger.Infof("No commitment recorded for project: %s po: %s ", projectId, poId)
+			continue
+		}
+		commitment := ProjectCommitment{}
+		json.Unmarshal(commitmentResponse, &commitment)
+		commitment.Actual += projectActuals[projectId]
+		commitmentBytes, _ := json.Marshal(commitment)
+		err = stub.PutPrivateData(PROJECT_COMMITMENT_COLLECTION, commitmentKey, commitmentBytes)
+		if err != nil {
+			return fmt.Errorf("unable to update the actual spend of po %s on project %s: %s", poId, projectId, err.Error())
+		}
+	}
+	return nil
+}
+
+/*
+	Method: projectTotalsForLineItems
+	Returns the value of a set of line items per project
+*/
+func projectTotalsForLineItems(poProjectId string, lineItems []LineItem) map[string]float64 {
+	projectTotals := make(map[string]float64)
+	for _, lineItem := range lineItems {
+		projectId := lineItem.ProjectId
+		if projectId == "" {
+			projectId = poProjectId
+		}
+		if projectId == "" {
+			continue
+		}
+		projectTotals[projectId] += lineItemValue(lineItem)
+	}
+	return projectTotals
+}
+
+func lineItemValue(lineItem LineItem) float64 {
+	if lineItem.Subtotal > 0 {
+		return lineItem.Subtotal
+	}
+	return math.Round(float64(lineItem.Quantity) * lineItem.UnitPrice)
+}
+
+func sortedProjectIds(projectTotals map[string]float64) []string {
+	projectIds := make([]string, 0, len(projectTotals))
+	for projectId := range projectTotals {
+		projectIds = append(projectIds, projectId)
+	}
+	sort.Strings(projectIds)
+	return projectIds
+}
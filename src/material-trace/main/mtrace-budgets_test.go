@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestLineItemValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		lineItem LineItem
+		want     float64
+	}{
+		{"subtotal wins", LineItem{Quantity: 10, UnitPrice: 5, Subtotal: 48}, 48},
+		{"quantity times price", LineItem{Quantity: 10, UnitPrice: 5}, 50},
+		{"rounded like the subtotal", LineItem{Quantity: 3, UnitPrice: 10.33}, 31},
+		{"nothing ordered", LineItem{UnitPrice: 5}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineItemValue(tt.lineItem); got != tt.want {
+				t.Errorf("lineItemValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectTotalsForLineItems(t *testing.T) {
+	lineItems := []LineItem{
+		{Quantity: 1, Subtotal: 100},
+		{Quantity: 2, Subtotal: 40, ProjectId: "P2"},
+		{Quantity: 1, Subtotal: 10, ProjectId: "P2"},
+	}
+	got := projectTotalsForLineItems("P1", lineItems)
+	if len(got) != 2 || got["P1"] != 100 || got["P2"] != 50 {
+		t.Errorf("projectTotalsForLineItems() = %v", got)
+	}
+	if got := projectTotalsForLineItems("", lineItems[:1]); len(got) != 0 {
+		t.Errorf("line items without a project are not counted, got %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_MATERIAL = "material"
+)
+
+/*
+	Method: setMaterial
+	Adds or updates an entry in the material master catalog. New entries are active by default.
+*/
+func (s *SmartContract) setMaterial(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. material")
+	}
+	material := Material{}
+	err := json.Unmarshal([]byte(args[0]), &material)
+	if err != nil {
+		return shim.Error("Unable to parse material data provided - " + args[0])
+	}
+	if material.MaterialId == "" || material.Description == "" || material.MaterialGroup == "" || material.BaseUnitOfMeasure == "" {
+		return shim.Error("materialId, description, materialGroup and baseUnitOfMeasure are required.")
+	}
+	key, err := stub.CreateCompositeKey(DOC_TYPE_MATERIAL, []string{material.MaterialId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// new entries are active unless "active" is given, updates keep the current flag
+	presence := struct {
+		Active *bool `json:"active"`
+	}{}
+	json.Unmarshal([]byte(args[0]), &presence)
+	if presence.Active == nil {
+		existing, found, err := getMaterial(stub, material.MaterialId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		material.Active = !found || existing.Active
+	}
+	material.ObjectType = DOC_TYPE_MATERIAL
+	material.UpdatedBy = organizationMap[currentMspId]
+	material.UpdatedTimeStamp = txTimeStamp(stub)
+	materialBytes, err := json.Marshal(material)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(key, materialBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(materialBytes)
+}
+
+/*
+	Method: queryMaterials
+	Returns the material catalog, optionally for a single material group
+*/
+func (s *SmartContract) queryMaterials(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	materialGroup := ""
+	if len(args) > 0 {
+		materialGroup = args[0]
+	}
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(DOC_TYPE_MATERIAL, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	materials := make([]Material, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		material := Material{}
+		json.Unmarshal(queryResponse.Value, &material)
+		if materialGroup != "" && !str.EqualFold(material.MaterialGroup, materialGroup) {
+			continue
+		}
+		materials = append(materials, material)
+	}
+	materialBytes, _ := json.Marshal(materials)
+	return shim.Success(materialBytes)
+}
+
+/*
+	Method: getMaterial
+	Returns the catalog entry for a material id, found is false when the material is not in the catalog
+*/
+func getMaterial(stub shim.ChaincodeStubInterface, materialId string) (Material, bool, error) {
+	material := Material{}
+	key, err := stub.CreateCompositeKey(DOC_TYPE_MATERIAL, []string{materialId})
+	if err != nil {
+		return material, false, err
+	}
+	materialBytes, err := stub.GetState(key)
+	if err != nil || materialBytes == nil {
+		return material, false, err
+	}
+	err = json.Unmarshal(materialBytes, &material)
+	return material, err == nil, err
+}
+
+/*
+	Method: applyMaterialCatalog
+	Validates a purchase order line against the catalog and fills in the canonical description and group
+*/
+func applyMaterialCatalog(stub shim.ChaincodeStubInterface, lineItem *LineItem) error {
+	material, found, err := getMaterial(stub, lineItem.MaterialId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("line %d: material %s is not in the material catalog", lineItem.LineNumber, lineItem.MaterialId)
+	}
+	if !material.Active {
+		return fmt.Errorf("line %d: material %s is not active", lineItem.LineNumber, lineItem.MaterialId)
+	}
+	if lineItem.UnitOfMeasure == "" {
+		lineItem.UnitOfMeasure = material.BaseUnitOfMeasure
+	} else if _, err := unitFactor(material, lineItem.UnitOfMeasure); err != nil {
+		return fmt.Errorf("line %d: unit of measure %s is not valid for material %s", lineItem.LineNumber, lineItem.UnitOfMeasure, lineItem.MaterialId)
+	}
+	lineItem.Description = material.Description
+	lineItem.MaterialGroup = material.MaterialGroup
+	return nil
+}
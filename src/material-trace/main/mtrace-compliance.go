@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_SPEC_REQUIREMENT  = "specRequirement"
+	DOC_TYPE_COMPLIANCE_WAIVER = "complianceWaiver"
+	COMPLIANCE_VERDICT_PASS    = "pass"
+	COMPLIANCE_VERDICT_FAIL    = "fail"
+	COMPLIANCE_VERDICT_WAIVED  = "waived"
+	COMPLIANCE_VERDICT_PENDING = "pending"
+)
+
+/*
+	Method: setSpecRequirement
+	Adds or replaces the requirements of an ordered spec
+*/
+func (s *SmartContract) setSpecRequirement(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. spec requirement")
+	}
+	requirement := SpecRequirement{}
+	err := json.Unmarshal([]byte(args[0]), &requirement)
+	if err != nil {
+		return shim.Error("Unable to parse spec requirement data provided - " + args[0])
+	}
+	if requirement.Spec == "" || len(requirement.Rules) == 0 {
+		return shim.Error("spec and at least one rule are required.")
+	}
+	for _, rule := range requirement.Rules {
+		if rule.Field == "" {
+			return shim.Error("Every rule needs a field.")
+		}
+	}
+	requirementKey, err := stub.CreateCompositeKey(DOC_TYPE_SPEC_REQUIREMENT, []string{str.ToLower(requirement.Spec)})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	requirement.ObjectType = DOC_TYPE_SPEC_REQUIREMENT
+	requirement.UpdatedBy = organizationMap[currentMspId]
+	requirement.UpdatedTimeStamp = txTimeStamp(stub)
+	requirementBytes, _ := json.Marshal(requirement)
+	err = stub.PutState(requirementKey, requirementBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(requirementBytes)
+}
+
+/*
+	Method: querySpecRequirements
+	Returns the requirements of every spec
+*/
+func (s *SmartContract) querySpecRequirements(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(DOC_TYPE_SPEC_REQUIREMENT, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	requirements := make([]SpecRequirement, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		requirement := SpecRequirement{}
+		json.Unmarshal(queryResponse.Value, &requirement)
+		requirements = append(requirements, requirement)
+	}
+	requirementBytes, _ := json.Marshal(requirements)
+	return shim.Success(requirementBytes)
+}
+
+/*
+	Method: waiveMtrCompliance
+	Executed when the customer or distributor accepts a certificate that failed its spec.
+	The waiver is kept in the general progress collection so manufacturers see it when shipping.
+*/
+func (s *SmartContract) waiveMtrCompliance(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. trackingId 2. reason")
+	}
+	if args[1] == "" {
+		return shim.Error("A reason is required to waive compliance.")
+	}
+	waiver := ComplianceWaiver{ObjectType: DOC_TYPE_COMPLIANCE_WAIVER, TrackingId: args[0], Reason: args[1], WaivedBy: organizationMap[currentMspId], TimeStamp: txTimeStamp(stub)}
+	waiverKey, err := stub.CreateCompositeKey(DOC_TYPE_COMPLIANCE_WAIVER, []string{waiver.TrackingId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	waiverBytes, _ := json.Marshal(waiver)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, waiverKey, waiverBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// the distributor can also mark the certificate itself
+	if currentMspId == "org2msp" {
+		if materialCert, privateCollection, found := getMaterialCertificate(stub, waiver.TrackingId); found && materialCert.Compliance.Verdict == COMPLIANCE_VERDICT_FAIL {
+			materialCert.Compliance.Verdict = COMPLIANCE_VERDICT_WAIVED
+			materialCert.Compliance.WaivedBy = waiver.WaivedBy
+			materialCert.Compliance.WaiverReason = waiver.Reason
+			mtrBytes, _ := json.Marshal(materialCert)
+			err = stub.PutPrivateData(privateCollection, materialCert.TrackingId, mtrBytes)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+	}
+	return shim.Success(waiverBytes)
+}
+
+/*
+	Method: queryMtrCompliance
+	Returns the verdict of a certificate against its own spec, or against an ordered spec when one is given
+*/
+func (s *SmartContract) queryMtrCompliance(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. trackingId 2. ordered spec (optional)")
+	}
+	materialCert, _, found := getMaterialCertificate(stub, args[0])
+	if !found {
+		return shim.Error("Material certificate not found - " + args[0])
+	}
+	spec := materialCert.Spec
+	if len(args) > 1 && args[1] != "" {
+		spec = args[1]
+	}
+	verdict := applyComplianceWaiver(stub, materialCert.TrackingId, evaluateCompliance(stub, materialCert, spec))
+	verdictBytes, _ := json.Marshal(verdict)
+	return shim.Success(verdictBytes)
+}
+
+/*
+	Method: evaluateCompliance
+	Compares the values of a certificate with the requirements of a spec
+*/
+func evaluateCompliance(stub shim.ChaincodeStubInterface, materialCert MaterialCertificate, spec string) ComplianceVerdict {
+	verdict := ComplianceVerdict{Verdict: COMPLIANCE_VERDICT_PENDING, Spec: spec, Failures: make([]string, 0)}
+	if spec == "" {
+		return verdict
+	}
+	if materialCert.Spec != "" && !str.EqualFold(materialCert.Spec, spec) {
+		verdict.Verdict = COMPLIANCE_VERDICT_FAIL
+		verdict.Failures = append(verdict.Failures, fmt.Sprintf("certificate is for %s, ordered %s", materialCert.Spec, spec))
+		return verdict
+	}
+	requirementKey, err := stub.CreateCompositeKey(DOC_TYPE_SPEC_REQUIREMENT, []string{str.ToLower(spec)})
+	if err != nil {
+		return verdict
+	}
+	requirementBytes, err := stub.GetState(requirementKey)
+	if err != nil || requirementBytes == nil {
+		return verdict
+	}
+	requirement := SpecRequirement{}
+	json.Unmarshal(requirementBytes, &requirement)
+	values := make(map[string]MtrDetails)
+	for _, detail := range materialCert.Data {
+		values[mtrFieldKey(detail.Name)] = detail
+	}
+	for _, rule := range requirement.Rules {
+		detail, found := values[mtrFieldKey(rule.Field)]
+		value := str.TrimSpace(detail.Value)
+		if !found || value == "" {
+			if rule.Required || rule.Min != nil || rule.Max != nil || rule.Equals != "" {
+				verdict.Failures = append(verdict.Failures, rule.Field+" is missing")
+			}
+			continue
+		}
+		if rule.Equals != "" && !str.EqualFold(value, rule.Equals) {
+			verdict.Failures = append(verdict.Failures, fmt.Sprintf("%s is %s, expecting %s", rule.Field, value, rule.Equals))
+		}
+		if rule.Min == nil && rule.Max == nil {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			verdict.Failures = append(verdict.Failures, rule.Field+" is not a number")
+			continue
+		}
+		if rule.Min != nil && number < *rule.Min {
+			verdict.Failures = append(verdict.Failures, fmt.Sprintf("%s %v below minimum %v %s", rule.Field, number, *rule.Min, rule.Unit))
+		}
+		if rule.Max != nil && number > *rule.Max {
+			verdict.Failures = append(verdict.Failures, fmt.Sprintf("%s %v above maximum %v %s", rule.Field, number, *rule.Max, rule.Unit))
+		}
+	}
+	verdict.Verdict = COMPLIANCE_VERDICT_PASS
+	if len(verdict.Failures) > 0 {
+		verdict.Verdict = COMPLIANCE_VERDICT_FAIL
+	}
+	return verdict
+}
+
+/*
+	Method: applyComplianceWaiver
+	Turns a failed verdict into a waived one when the certificate has been waived
+*/
+func applyComplianceWaiver(stub shim.ChaincodeStubInterface, trackingId string, verdict ComplianceVerdict) ComplianceVerdict {
+	if verdict.Verdict != COMPLIANCE_VERDICT_FAIL {
+		return verdict
+	}
+	waiverKey, err := stub.CreateCompositeKey(DOC_TYPE_COMPLIANCE_WAIVER, []string{trackingId})
+	if err != nil {
+		return verdict
+	}
+	waiverBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, waiverKey)
+	if err != nil || waiverBytes == nil {
+		return verdict
+	}
+	waiver := ComplianceWaiver{}
+	json.Unmarshal(waiverBytes, &waiver)
+	verdict.Verdict = COMPLIANCE_VERDICT_WAIVED
+	verdict.WaivedBy = waiver.WaivedBy
+	verdict.WaiverReason = waiver.Reason
+	return verdict
+}
+
+/*
+	Method: checkLineCompliance
+	Evaluates the certificates linked to a line against the spec ordered for its material.
+	Returns the combined verdict, and an error when the material group requires a passing verdict
+	and a certificate fails or is missing. A line without any verdict yet stays pending;
+	whether pending blocks shipment is up to the caller.
+*/
+func checkLineCompliance(stub shim.ChaincodeStubInterface, lineNumber int, materialId string, materialGroup string, trackingIds []string) (string, error) {
+	orderedSpec := ""
+	if material, found, _ := getMaterial(stub, materialId); found {
+		orderedSpec = materialSpecName(material.Spec)
+	}
+	failures := make([]string, 0)
+	pending, waived, passed := false, false, false
+	for _, trackingId := range trackingIds {
+		materialCert, _, found := getMaterialCertificate(stub, trackingId)
+		if !found {
+			failures = append(failures, "certificate "+trackingId+" not found")
+			continue
+		}
+		spec := orderedSpec
+		if spec == "" {
+			spec = materialCert.Spec
+		}
+		verdict := applyComplianceWaiver(stub, trackingId, evaluateCompliance(stub, materialCert, spec))
+		switch verdict.Verdict {
+		case COMPLIANCE_VERDICT_FAIL:
+			failures = append(failures, trackingId+" is "+verdict.Verdict)
+		case COMPLIANCE_VERDICT_PENDING:
+			pending = true
+		case COMPLIANCE_VERDICT_WAIVED:
+			waived = true
+		case COMPLIANCE_VERDICT_PASS:
+			passed = true
+		}
+	}
+	combined := COMPLIANCE_VERDICT_PENDING
+	switch {
+	case len(failures) > 0:
+		combined = COMPLIANCE_VERDICT_FAIL
+	case pending:
+		combined = COMPLIANCE_VERDICT_PENDING
+	case waived:
+		combined = COMPLIANCE_VERDICT_WAIVED
+	case passed:
+		combined = COMPLIANCE_VERDICT_PASS
+	}
+	template, found := getMtrTemplate(stub, materialGroup)
+	if found && template.RequirePassingVerdict && combined == COMPLIANCE_VERDICT_FAIL {
+		return combined, fmt.Errorf("line %d cannot ship without a passing compliance verdict: %s", lineNumber, str.Join(failures, "; "))
+	}
+	return combined, nil
+}
+
+/*
+	Method: getMaterialCertificate
+	Looks up a certificate in the MTR collections the caller can read
+*/
+func getMaterialCertificate(stub shim.ChaincodeStubInterface, trackingId string) (MaterialCertificate, string, bool) {
+	materialCert := MaterialCertificate{}
+	collections := []string{mtrCollection(currentMspId)}
+	if currentMspId == "org2msp" {
+		collections = []string{PRIVATE_COLLECTION_MTR_MFR1, PRIVATE_COLLECTION_MTR_MFR2}
+	}
+	for _, privateCollection := range collections {
+		if privateCollection == "" {
+			continue
+		}
+		mtrBytes, err := stub.GetPrivateData(privateCollection, trackingId)
+		if err == nil && mtrBytes != nil {
+			json.Unmarshal(mtrBytes, &materialCert)
+			return materialCert, privateCollection, true
+		}
+	}
+	return materialCert, "", false
+}
+
+/*
+	Method: linkedTrackingIds
+	Returns the certificate tracking ids a line carries in its materialCertificate name/value list
+*/
+func linkedTrackingIds(mtrs []Mtr) []string {
+	trackingIds := make([]string, 0)
+	for _, mtr := range mtrs {
+		if mtrFieldKey(mtr.Name) == "trackingid" && mtr.Value != "" && !containsFold(trackingIds, mtr.Value) {
+			trackingIds = append(trackingIds, mtr.Value)
+		}
+	}
+	return trackingIds
+}
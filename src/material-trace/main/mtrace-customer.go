@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/http"
 	"strconv"
+	str "strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	sc "github.com/hyperledger/fabric/protos/peer"
@@ -19,6 +20,7 @@ func (s *SmartContract) handleCreatePoRequest(stub shim.ChaincodeStubInterface,
 		msg := fmt.Sprintf("purchase order with id %s exists", id)
 		return Error(http.StatusConflict, msg)
 	}
+	item.ObjectType = DOC_TYPE_PURCHASE_ORDER
 	item.PoStatus = STATUS_OPEN
 	// item.Custodian = "Customer"
 	// item.CurrentJourney = Journey{
@@ -36,6 +38,16 @@ func (s *SmartContract) handleCreatePoRequest(stub shim.ChaincodeStubInterface,
 	// 	Status:    STATUS_OPEN,
 	// 	TimeStamp: item.CreatedTimeStamp,
 	// }
+	// validate lines against the material catalog before anything is written
+	catalogErrors := make([]string, 0)
+	for i := range item.LineItems {
+		if err := applyMaterialCatalog(stub, &item.LineItems[i]); err != nil {
+			catalogErrors = append(catalogErrors, err.Error())
+		}
+	}
+	if len(catalogErrors) > 0 {
+		return Error(http.StatusBadRequest, str.Join(catalogErrors, "; "))
+	}
 	sharedDetails := make([]SharedLineDetail, 1)
 	for i, lineItem := range item.LineItems {
 		key := generateItemKey(item.PoId, lineItem)
@@ -66,8 +78,28 @@ func (s *SmartContract) handleCreatePoRequest(stub shim.ChaincodeStubInterface,
 		pdLineItem.LineItems[i] = lineItem
 	}
 
+	// check and commit spend against project budgets
+	projectTotals := projectTotalsForLineItems(item.ProjectId, pdLineItem.LineItems)
+	budgetWarnings, err := checkProjectBudgets(stub, projectTotals)
+	if err != nil {
+		return Error(http.StatusForbidden, err.Error())
+	}
+	if err := recordProjectCommitments(stub, item.PoId, item.PoNumber, projectTotals, STATUS_OPEN, item.CreatedTimeStamp); err != nil {
+		return shim.Error(err.Error())
+	}
+
 	// will use this object to share the progress status on PM screen
 	addNewShareProgressRecord(stub, item.PoId, sharedDetails)
+	for _, lineItem := range pdLineItem.LineItems {
+		projectId := lineItem.ProjectId
+		if projectId == "" {
+			projectId = item.ProjectId
+		}
+		if projectId == "" {
+			continue
+		}
+		recordGenealogyEdge(stub, PRIVATE_COLLECTION_GENERAL_PROGRESS, GenealogyEdge{FromType: GENEALOGY_NODE_PO_LINE, FromId: lineItem.ItemKey, ToType: GENEALOGY_NODE_PROJECT, ToId: projectId, PoId: item.PoId, TimeStamp: item.CreatedTimeStamp})
+	}
 
 	poLineItems := pdLineItem.LineItems // will be returned to ui client
 	item.LineItems = make([]LineItem, 0)
@@ -85,6 +117,7 @@ func (s *SmartContract) handleCreatePoRequest(stub shim.ChaincodeStubInterface,
 		return shim.Error(err.Error())
 	}
 	item.LineItems = poLineItems
+	item.BudgetWarnings = budgetWarnings
 	var event = CustomEvent{Type: "pocreated", Description: "Po Created", Status: item.PoStatus, Id: item.PoId, PoNumber: item.PoNumber, LineItems: item.LineItems}
 	eventBytes, err := json.Marshal(&event)
 	if err != nil {
@@ -102,14 +135,34 @@ func (s *SmartContract) handleCreatePoRequest(stub shim.ChaincodeStubInterface,
 
 }
 func (s *SmartContract) handleValidateOrderRequest(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) == 1 {
+		return s.verifyLoadReceived(stub, args[0])
+	}
 	if len(args) != 2 {
-		return shim.Error("Expecting two arguments 1. poId 2. ShippingRequestId")
+		return shim.Error("Expecting two arguments 1. poId 2. ShippingRequestId, or a single loadId")
 	}
 	poId := args[0]
 	shippingRequestNumber, err := strconv.ParseInt(args[1], 10, 64)
 	if err != nil {
 		return shim.Error("Invalid number format, expecting a numbeer on argument 2")
 	}
+	shippedLineItems, err := verifyReceivedItems(stub, poId, []int64{shippingRequestNumber})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(shippedLineItems) == 0 {
+		shippedLineItems = make([]GoodReceipt, 1)
+	}
+	rsBytes, _ := json.Marshal(shippedLineItems)
+	return shim.Success(rsBytes)
+}
+
+/*
+	Method: verifyReceivedItems
+	Marks the lines of a PO carried by the given shipping requests as verified and returns
+	the good receipts comparing what was received with what was ordered
+*/
+func verifyReceivedItems(stub shim.ChaincodeStubInterface, poId string, shippingRequestNumbers []int64) ([]GoodReceipt, error) {
 	collectionName := PRIVATE_COLLECTION_CUSTOMER_LINEITEMS
 	indexMap := make(map[string]int)
 	indexByLineNumberMap := make(map[int]int)
@@ -126,16 +179,32 @@ func (s *SmartContract) handleValidateOrderRequest(stub shim.ChaincodeStubInterf
 			}
 		}
 	}
-	logisticsResponse, err1 := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
+	privateData := ShippingRequest{LineItems: make([]ShippingLineItem, 0)}
+	requestNumbers := make(map[int64]bool)
+	for _, shippingRequestNumber := range shippingRequestNumbers {
+		shippingRequest, found, err1 := getShippingRequest(stub, shippingRequestNumber)
+		if err1 == nil && found && shippingRequest.PoId == poId {
+			privateData.LineItems = append(privateData.LineItems, shippingRequest.LineItems...)
+			requestNumbers[shippingRequestNumber] = true
+		}
+	}
 	// shippedLineItems := make([]ShippingLineItem, 1)
 	updatedCount := 0
-	updatedLineItems := make([]LineItem, 1)
-	shippedLineItems := make([]GoodReceipt, 1)
-	if err1 == nil && logisticsResponse != nil {
-		privateData := ShippingRequest{}
-		json.Unmarshal(logisticsResponse, &privateData)
-		for i, shippingInfo := range privateData.LineItems {
-			if shippingInfo.ShippingRequestNumber != shippingRequestNumber {
+	shippedLineItems := make([]GoodReceipt, 0)
+	verifiedLineItems := make([]LineItem, 0)
+	if len(requestNumbers) > 0 {
+		// material under an open recall cannot be accepted
+		heldLineNumbers := make([]int, 0)
+		for _, shippingInfo := range privateData.LineItems {
+			if requestNumbers[shippingInfo.ShippingRequestNumber] {
+				heldLineNumbers = append(heldLineNumbers, shippingInfo.LineNumber)
+			}
+		}
+		if err := checkRecallHolds(stub, poId, heldLineNumbers); err != nil {
+			return nil, err
+		}
+		for _, shippingInfo := range privateData.LineItems {
+			if !requestNumbers[shippingInfo.ShippingRequestNumber] {
 				continue
 			}
 			index, found := indexByLineNumberMap[shippingInfo.LineNumber]
@@ -153,21 +222,22 @@ func (s *SmartContract) handleValidateOrderRequest(stub shim.ChaincodeStubInterf
 					break
 				}
 			}
-			pLineItem.LineItems[index].Status = STATUS_VERIFIED
-			if updatedCount == 0 {
-				updatedLineItems[0] = pLineItem.LineItems[i]
-			} else {
-				updatedLineItems = append(updatedLineItems, pLineItem.LineItems[i])
+			if pLineItem.LineItems[index].Status != STATUS_VERIFIED {
+				verifiedLineItems = append(verifiedLineItems, pLineItem.LineItems[index])
+				receivedStatus := ItemStatus{Owner: organizationMap["org1msp"], Status: STATUS_RECEIVED, TimeStamp: pLineItem.LineItems[index].TimeReceived}
+				updateSerializedUnitStatus(stub, poId, pLineItem.LineItems[index].ItemKey, "", STATUS_SHIPPED, receivedStatus, nil)
 			}
+			pLineItem.LineItems[index].Status = STATUS_VERIFIED
 
 			goodReciept := GoodReceipt{
 				MaterialCertificate: pLineItem.LineItems[index].MaterialCertificate,
 				ShippedLineItem:     shippingInfo}
-			if updatedCount == 0 {
-				shippedLineItems[0] = goodReciept
-			} else {
-				shippedLineItems = append(shippedLineItems, goodReciept)
-			}
+			// compare what was received with what was ordered in the base unit of the material
+			ordered := pLineItem.LineItems[index]
+			goodReciept.OrderedBaseQuantity, goodReciept.BaseUnitOfMeasure, _ = baseQuantity(stub, ordered.MaterialId, float64(ordered.Quantity), ordered.UnitOfMeasure)
+			goodReciept.ReceivedBaseQuantity, _, _ = baseQuantity(stub, ordered.MaterialId, float64(shippingInfo.Quantity), shippingInfo.UnitOfMeasure)
+			goodReciept.QuantityVariance = roundUnits(goodReciept.ReceivedBaseQuantity - goodReciept.OrderedBaseQuantity)
+			shippedLineItems = append(shippedLineItems, goodReciept)
 
 			updatedCount += 1
 		}
@@ -184,9 +254,17 @@ func (s *SmartContract) handleValidateOrderRequest(stub shim.ChaincodeStubInterf
 				// return shim.Error(err.Error())
 			}
 		}
+		if len(verifiedLineItems) > 0 {
+			po := PurchaseOrder{}
+			if value, err := stub.GetState(poId); err == nil && value != nil {
+				json.Unmarshal(value, &po)
+			}
+			if err := recordProjectActuals(stub, poId, po.ProjectId, verifiedLineItems); err != nil {
+				return nil, err
+			}
+		}
 	}
-	rsBytes, _ := json.Marshal(shippedLineItems)
-	return shim.Success(rsBytes)
+	return shippedLineItems, nil
 
 	// resMsg := ResponseMessage{}
 	// // responseMessage
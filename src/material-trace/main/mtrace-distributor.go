@@ -18,6 +18,10 @@ import (
 	If po is rejected, the status is set to rejected and process stops.
 	Otherwise the lineitems are split based on assignedTo value - items can go to either inventory from distributor,
 	manufacturerer 1, or manufacturer 2.
+	Manufacturers must be on the approved vendor list for the line, lines of a material group without
+	any approved vendors are accepted and flagged in vendorWarnings.
+	Items from inventory are reserved in the distributor inventory ledger. A line assigned entirely to inventory
+	is refused when stock is insufficient, while the inventory share of a split line falls back to the manufacturer.
 	The split items are stored in private collection databases.
 */
 func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string) sc.Response {
@@ -72,6 +76,18 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 	if !isAccepted {
 		po.PoStatus = STATUS_REJECTED
 		po.Comment = args[3]
+		// release the spend committed to project budgets
+		rejectedLineItems := LineItemPrivateDetails{}
+		if poPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, po.PoId); err == nil && poPrivateDataResponse != nil {
+			json.Unmarshal(poPrivateDataResponse, &rejectedLineItems)
+		}
+		releasedTotals := projectTotalsForLineItems(po.ProjectId, rejectedLineItems.LineItems)
+		for projectId := range releasedTotals {
+			releasedTotals[projectId] = 0
+		}
+		if err := recordProjectCommitments(stub, po.PoId, po.PoNumber, releasedTotals, STATUS_REJECTED, acceptanceTimeStamp); err != nil {
+			return shim.Error(err.Error())
+		}
 		if err := stub.PutState(po.PoId, po.ToJson()); err != nil {
 			return shim.Error(err.Error())
 		}
@@ -108,27 +124,46 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 	}
 	validMfrs := "manufacturer 1|manufacturer 2"
 	sharedItemsMap := make(map[int]LineItem)
+	inventoryCache := make(map[string]*InventoryRecord)
+	vendorWarnings := make([]string, 0)
 	for i, lineItem := range po.LineItems {
 		updatedItem := lineItemMap[lineItem.LineNumber] // lineItem.MaterialId]
 		lineItem.DeliveryDate = updatedItem.DeliveryDate
-		lineItem.AssignedQty = updatedItem.AssignedQty
 		orderRequests := make([]OrderRequest, 1)
 		orderSplitCount := 0
 		distributorQty := 0
 		assignedToMfr := ""
+		available := availableInventory(stub, inventoryCache, lineItem.MaterialId, lineItem.UnitOfMeasure)
 		// items that will be fulfilled by distributor
 		if updatedItem.AssignedTo == "" || str.ToLower(updatedItem.AssignedTo) == "inventory" {
-			// distributorQty := updatedItem.Quantity - updatedItem.AssignedQty
 			distributorQty = updatedItem.Quantity
 			updatedItem.AssignedTo = "Inventory"
+			if available < distributorQty {
+				return shim.Error(fmt.Sprintf("Insufficient inventory for line %d material %s. Requested: %d Available: %d", lineItem.LineNumber, lineItem.MaterialId, distributorQty, available))
+			}
 		} else {
-			updatedItem.AssignedQty = updatedItem.Quantity
+			// a partial assignment fills the rest from inventory, any shortfall goes back to the manufacturer
+			if updatedItem.AssignedQty <= 0 || updatedItem.AssignedQty > updatedItem.Quantity {
+				updatedItem.AssignedQty = updatedItem.Quantity
+			}
+			distributorQty = updatedItem.Quantity - updatedItem.AssignedQty
+			if available < distributorQty {
+				if available < 0 {
+					available = 0
+				}
+				updatedItem.AssignedQty += distributorQty - available
+				distributorQty = available
+			}
 			// updatedItem.AssignToMfr = updatedItem.AssignedTo
 			assignedToMfr = updatedItem.AssignedTo
 		}
+		lineItem.AssignedQty = updatedItem.AssignedQty
 		sharedItemsMap[lineItem.LineNumber] = updatedItem
 		if distributorQty > 0 {
-			pricingInfo := fillPricingInfo(lineItem, updatedItem.DeliveryDate, updatedItem.AssignedTo, distributorQty, updatedItem.UnitPrice, po.PoNumber, po.PoId, utilityInitialStatus, distributorProgressStatus)
+			if err := reserveInventory(stub, inventoryCache, lineItem.MaterialId, distributorQty, lineItem.UnitOfMeasure, lineItem.ItemKey, acceptanceTimeStamp); err != nil {
+				return shim.Error(err.Error())
+			}
+			pricingInfo := fillPricingInfo(stub, lineItem, updatedItem.DeliveryDate, "Inventory", distributorQty, updatedItem.UnitPrice, po.PoNumber, po.PoId, utilityInitialStatus, distributorProgressStatus)
 			if distributorAssignedCount == 0 {
 				cdDistributorLineItem.LineItems = make([]LineItemPricing, 1)
 				cdDistributorLineItem.ObjectType = PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR
@@ -163,9 +198,17 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 			if discountInfo.Name == "" || discountInfo.Discount == 0 {
 				return shim.Error("Manufacturer discount missing for " + assignedToMfr)
 			}
+			// qualification is checked at the transaction time, not the time given by the caller
+			if vendorCheck := checkApprovedVendor(stub, lineItem, assignedToMfr, txTimeStamp(stub)); !vendorCheck.Eligible {
+				// without AVL entries for the group only the catalog exclusion is enforced
+				if vendors, _ := getApprovedVendors(stub, lineItem.MaterialGroup); len(vendors) > 0 || !approvedByCatalog(stub, lineItem.MaterialId, assignedToMfr) {
+					return shim.Error(fmt.Sprintf("Line %d cannot be assigned: %s", lineItem.LineNumber, vendorCheck.Reason))
+				}
+				vendorWarnings = append(vendorWarnings, fmt.Sprintf("line %d: %s", lineItem.LineNumber, vendorCheck.Reason))
+			}
 			discountedPrice := (lineItem.UnitPrice) - (float64(discountInfo.Discount) / 100 * lineItem.UnitPrice)
 			updatedItem.MfrUnitPrice = math.Round(discountedPrice)
-			pricingInfo := fillPricingInfo(lineItem, updatedItem.DeliveryDate, updatedItem.AssignedTo, updatedItem.AssignedQty, updatedItem.MfrUnitPrice, po.PoNumber, po.PoId, utilityInitialStatus, distributorProgressStatus)
+			pricingInfo := fillPricingInfo(stub, lineItem, updatedItem.DeliveryDate, updatedItem.AssignedTo, updatedItem.AssignedQty, updatedItem.MfrUnitPrice, po.PoNumber, po.PoId, utilityInitialStatus, distributorProgressStatus)
 			// who is supplying specific items
 			orderRequest := OrderRequest{}
 			orderRequest.LineNumber = lineItem.LineNumber
@@ -263,8 +306,15 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 			return shim.Error(err.Error())
 		}
 	}
+	if err := commitInventory(stub, inventoryCache); err != nil {
+		return shim.Error(err.Error())
+	}
 	// Add progress to shared table
 	updateSharedProgressRecord(stub, poId, sharedItemsMap, progressStatus, MODE_DISTRIBUTOR_ACCEPTS)
+	// commit accepted spend against project budgets
+	if err := recordProjectCommitments(stub, po.PoId, po.PoNumber, projectTotalsForLineItems(po.ProjectId, po.LineItems), STATUS_ACCEPTED, acceptanceTimeStamp); err != nil {
+		return shim.Error(err.Error())
+	}
 	// submit changes to purchase order
 	poLineItems := po.LineItems
 	po.LineItems = make([]LineItem, 1) // remove lineItems from primary db, line items will be stored in priviate collections.
@@ -273,6 +323,9 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 	}
 
 	po.LineItems = poLineItems // return for client consumption
+	if len(vendorWarnings) > 0 {
+		po.VendorWarnings = vendorWarnings
+	}
 	var event = CustomEvent{Type: "poaccepted", Description: STATUS_ACCEPTED, Status: po.PoStatus, Id: po.PoId, PoNumber: po.PoNumber, LineItems: po.LineItems}
 	eventBytes, err := json.Marshal(&event)
 	if err != nil {
@@ -292,7 +345,7 @@ func (s *SmartContract) acceptPo(stub shim.ChaincodeStubInterface, args []string
 	Method: fillPricingInfo
 	This is a utility method to fill private collection lineitems
 */
-func fillPricingInfo(lineItem LineItem, DeliveryDate string, assignedTo string, quantity int, unitCost float64, poNumber int, poId string, utilityInitialStatus ItemStatus, distributorInitialStatus ItemStatus) LineItemPricing {
+func fillPricingInfo(stub shim.ChaincodeStubInterface, lineItem LineItem, DeliveryDate string, assignedTo string, quantity int, unitCost float64, poNumber int, poId string, utilityInitialStatus ItemStatus, distributorInitialStatus ItemStatus) LineItemPricing {
 	pricingInfo := LineItemPricing{}
 	pricingInfo.PoId = poId
 	pricingInfo.PoNumber = poNumber
@@ -324,7 +377,7 @@ func fillPricingInfo(lineItem LineItem, DeliveryDate string, assignedTo string,
 		pricingInfo.ProgressStatus[0] = distributorInitialStatus
 	}
 
-	return pricingInfo
+	return normalizePricing(stub, pricingInfo)
 
 }
 
@@ -366,7 +419,9 @@ func (s *SmartContract) advanceInTransitItem(stub shim.ChaincodeStubInterface, a
 				} else {
 					logger.Infof("status doesn't match; expected %s but found %s ", STATUS_SHIPPED, itemPrivateData.LineItems[i].Status)
 				}
-				markAsDeliveredLogistics(stub, key, item.ItemKey, progressStatus)
+				if err := markAsDeliveredLogistics(stub, key, item.ItemKey, progressStatus); err != nil {
+					return shim.Error(err.Error())
+				}
 			} else {
 				logger.Infof("key not found")
 			}
@@ -423,32 +478,37 @@ func markAsDelivered(stub shim.ChaincodeStubInterface, privateCollectionName str
 	Method: markAsDeliveredLogistics
 	utility method to mark item as in logistics table
 */
-func markAsDeliveredLogistics(stub shim.ChaincodeStubInterface, poId string, itemKey string, progressStatus ItemStatus) {
+func markAsDeliveredLogistics(stub shim.ChaincodeStubInterface, poId string, itemKey string, progressStatus ItemStatus) error {
 
-	privateDataResponse, err1 := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
+	shippingRequests, err1 := getShippingRequestsForPo(stub, poId)
 	if err1 != nil {
-		return
+		return nil
 	}
-	pricingInfo := LineItemCDPrivateDetails{}
-	json.Unmarshal(privateDataResponse, &pricingInfo)
-	updatedCount := 0
-	for i, item := range pricingInfo.LineItems {
-		if pricingInfo.LineItems[i].Status == STATUS_SHIPPED && item.ItemKey == itemKey {
-			pricingInfo.LineItems[i].Status = progressStatus.Status
-			updatedCount += 1
+	for _, shippingRequest := range shippingRequests {
+		updatedCount := 0
+		for i, item := range shippingRequest.LineItems {
+			if (item.Status == STATUS_SHIPPED || item.Status == STATUS_IN_TRANSIT) && item.ItemKey == itemKey {
+				shippingRequest.LineItems[i].Status = progressStatus.Status
+				shippingRequest.LineItems[i].ProgressStatus = append(shippingRequest.LineItems[i].ProgressStatus, progressStatus)
+				updatedCount += 1
+			}
+		}
+		if updatedCount < 1 {
+			continue
+		}
+		refreshShippingRequestStatus(&shippingRequest)
+		if shippingRequest.Status == STATUS_DELIVERED && shippingRequest.TimeDelivered == 0 {
+			shippingRequest.TimeDelivered = progressStatus.TimeStamp
+		}
+		err := commitShippingRequest(stub, shippingRequest)
+		if err != nil {
+			logger.Error(err.Error())
+		}
+		if err := syncAwardedTender(stub, shippingRequest); err != nil {
+			return err
 		}
 	}
-	if updatedCount < 1 {
-		return
-	}
-	pricingBytes, err := json.Marshal(pricingInfo)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	err = stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId, pricingBytes)
-	if err != nil {
-		logger.Error(err.Error())
-	}
+	return nil
 }
 
 /*
@@ -609,6 +669,8 @@ func (s *SmartContract) notifyDistributorOnLogisticsShipment(stub shim.Chaincode
 	if err != nil {
 		return shim.Error("Unable to parse progress status data provided - " + args[3])
 	}
+	// the carrier only recorded the pickup on its tender
+	syncShippingRequestsFromTenders(stub, poId)
 	poPrivateDataResponse, err1 := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, poId)
 	if err1 != nil {
 		logger.Info("notifyDistributorOnMfrShipment: Error, Unable to get " + PRIVATE_COLLECTION_CUSTOMER_LINEITEMS + " data for PO: " + poId)
@@ -655,7 +717,8 @@ func (s *SmartContract) notifyDistributorOnLogisticsShipment(stub shim.Chaincode
 /*
 	Method: notifyItemDelivered
 	Executed based on event triggered when geolocattion calculations indicate the item has reached destination.
-	This allows the distributor to update the main private collection shared between customer and distributor
+	This allows the distributor to update the main private collection shared between customer and distributor.
+	The event of a consolidated load carries one delivery per PO on the load.
 */
 func (s *SmartContract) notifyItemDelivered(stub shim.ChaincodeStubInterface, args []string) sc.Response {
 	logger.Info("called notifyItemDelivered")
@@ -675,6 +738,27 @@ func (s *SmartContract) notifyItemDelivered(stub shim.ChaincodeStubInterface, ar
 	if err != nil {
 		return shim.Error("Unable to parse progress status data provided - " + args[2])
 	}
+	deliveries := event.Deliveries
+	if len(deliveries) == 0 {
+		deliveries = []ItemDeliveryEvent{event}
+	}
+	for _, delivery := range deliveries {
+		recordItemDelivered(stub, collectionName, delivery, progressStatus)
+		// the delivery may have been recorded by an org outside the carrier's collection
+		if _, err := syncAwardedTendersForPo(stub, delivery.PoId); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	eventBytes, _ := json.Marshal(event)
+	return shim.Success(eventBytes)
+
+}
+
+/*
+	Method: recordItemDelivered
+	Marks the lines of one PO carried under the delivered tracking code as received
+*/
+func recordItemDelivered(stub shim.ChaincodeStubInterface, collectionName string, event ItemDeliveryEvent, progressStatus ItemStatus) {
 	poPrivateDataResponse, err2 := stub.GetPrivateData(collectionName, event.PoId)
 	if err2 != nil {
 		logger.Info("unable to find private data for: " + event.PoId + " in collection: " + collectionName + " error: " + err2.Error())
@@ -712,9 +796,6 @@ func (s *SmartContract) notifyItemDelivered(stub shim.ChaincodeStubInterface, ar
 			}
 		}
 	}
-	eventBytes, _ := json.Marshal(event)
-	return shim.Success(eventBytes)
-
 }
 
 /*
@@ -773,7 +854,7 @@ func updateDistributorIncomingIOT(stub shim.ChaincodeStubInterface, poId string,
 
 	privateCollection := PRIVATE_COLLECTION_CUSTOMER_LINEITEMS
 	poPrivateDataResponse, err1 := stub.GetPrivateData(privateCollection, poId)
-	if err1 != nil {
+	if err1 != nil || poPrivateDataResponse == nil {
 		// log poId not found
 		logger.Info("unable to find data for: " + poId + " in collection: " + privateCollection)
 		return false
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_GENEALOGY_EDGE      = "genealogy"
+	DOC_TYPE_GENEALOGY_REVERSE   = "genealogyReverse"
+	GENEALOGY_NODE_HEAT          = "heat"
+	GENEALOGY_NODE_MTR           = "mtr"
+	GENEALOGY_NODE_ORDER_REQUEST = "orderRequest"
+	GENEALOGY_NODE_SHIPMENT      = "shipment"
+	GENEALOGY_NODE_PO_LINE       = "poLine"
+	GENEALOGY_NODE_PROJECT       = "project"
+	GENEALOGY_FORWARD            = "forward"
+	GENEALOGY_BACKWARD           = "backward"
+	GENEALOGY_MAX_DEPTH          = 10
+)
+
+/*
+	Method: traceGenealogy
+	Walks the genealogy forward (where did heat X go?) or backward (what heat is the pipe on project Y made from?).
+	Only collections the calling organization can read are searched, so the trace stops where access ends.
+*/
+func (s *SmartContract) traceGenealogy(stub shim.ChaincodeStubInterface, args []string, direction string) sc.Response {
+
+	if len(args) < 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. node type 2. node id 3. depth (optional)")
+	}
+	depth := GENEALOGY_MAX_DEPTH
+	if len(args) > 2 {
+		parsed, err := strconv.Atoi(args[2])
+		if err != nil || parsed < 1 {
+			return shim.Error("Unable to parse depth provided - " + args[2] + " Expecting a positive number.")
+		}
+		if parsed < depth {
+			depth = parsed
+		}
+	}
+	collections := genealogyCollections(currentMspId)
+	if len(collections) == 0 {
+		return shim.Error("Unexpected organization, no genealogy data available for " + currentMspId)
+	}
+	trace := GenealogyTrace{NodeType: args[0], NodeId: args[1], Direction: direction}
+	trace.Edges = walkGenealogy(stub, collections, args[0], args[1], direction, depth)
+	traceBytes, _ := json.Marshal(trace)
+	return shim.Success(traceBytes)
+}
+
+/*
+	Method: walkGenealogy
+	Breadth first walk from a node returning every edge reached within depth
+*/
+func walkGenealogy(stub shim.ChaincodeStubInterface, collections []string, nodeType string, nodeId string, direction string, depth int) []GenealogyEdge {
+	edges := make([]GenealogyEdge, 0)
+	visitedNodes := map[string]bool{nodeType + "|" + nodeId: true}
+	visitedEdges := make(map[string]bool)
+	frontier := [][]string{{nodeType, nodeId}}
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		next := make([][]string, 0)
+		for _, node := range frontier {
+			for _, edge := range getGenealogyEdges(stub, collections, node[0], node[1], direction) {
+				edgeId := str.Join([]string{edge.FromType, edge.FromId, edge.ToType, edge.ToId}, "|")
+				if visitedEdges[edgeId] {
+					continue
+				}
+				visitedEdges[edgeId] = true
+				edges = append(edges, edge)
+				nextNode := []string{edge.ToType, edge.ToId}
+				if direction == GENEALOGY_BACKWARD {
+					nextNode = []string{edge.FromType, edge.FromId}
+				}
+				if !visitedNodes[nextNode[0]+"|"+nextNode[1]] {
+					visitedNodes[nextNode[0]+"|"+nextNode[1]] = true
+					next = append(next, nextNode)
+				}
+			}
+		}
+		frontier = next
+	}
+	return edges
+}
+
+/*
+	Method: recordGenealogyEdge
+	Stores an edge under a forward and a reverse key in the given collection
+*/
+func recordGenealogyEdge(stub shim.ChaincodeStubInterface, privateCollection string, edge GenealogyEdge) {
+	if edge.FromId == "" || edge.ToId == "" {
+		return
+	}
+	edge.ObjectType = DOC_TYPE_GENEALOGY_EDGE
+	edge.RecordedBy = organizationMap[currentMspId]
+	edgeBytes, err := json.Marshal(edge)
+	if err != nil {
+		logger.Infof("Unable to marshal genealogy edge: %s", err.Error())
+		return
+	}
+	forwardKey, err := stub.CreateCompositeKey(DOC_TYPE_GENEALOGY_EDGE, []string{edge.FromType, edge.FromId, edge.ToType, edge.ToId})
+	if err == nil {
+		err = stub.PutPrivateData(privateCollection, forwardKey, edgeBytes)
+	}
+	if err != nil {
+		logger.Infof("Unable to record genealogy edge in %s: %s", privateCollection, err.Error())
+		return
+	}
+	reverseKey, err := stub.CreateCompositeKey(DOC_TYPE_GENEALOGY_REVERSE, []string{edge.ToType, edge.ToId, edge.FromType, edge.FromId})
+	if err == nil {
+		err = stub.PutPrivateData(privateCollection, reverseKey, edgeBytes)
+	}
+	if err != nil {
+		logger.Infof("Unable to record reverse genealogy edge in %s: %s", privateCollection, err.Error())
+	}
+}
+
+/*
+	Method: getGenealogyEdges
+	Returns the edges leaving (forward) or entering (backward) a node across the given collections
+*/
+func getGenealogyEdges(stub shim.ChaincodeStubInterface, collections []string, nodeType string, nodeId string, direction string) []GenealogyEdge {
+	objectType := DOC_TYPE_GENEALOGY_EDGE
+	if direction == GENEALOGY_BACKWARD {
+		objectType = DOC_TYPE_GENEALOGY_REVERSE
+	}
+	edges := make([]GenealogyEdge, 0)
+	for _, privateCollection := range collections {
+		resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection, objectType, []string{nodeType, nodeId})
+		if err != nil {
+			logger.Infof("Unable to read genealogy from %s: %s", privateCollection, err.Error())
+			continue
+		}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				break
+			}
+			edge := GenealogyEdge{}
+			json.Unmarshal(queryResponse.Value, &edge)
+			edges = append(edges, edge)
+		}
+		resultsIterator.Close()
+	}
+	return edges
+}
+
+/*
+	Method: genealogyCollections
+	Collections holding genealogy edges that an organization is a member of, see collections_config.json
+*/
+func genealogyCollections(mspId string) []string {
+	switch mspId {
+	case "org1msp", "org6msp", "org7msp":
+		return []string{PRIVATE_COLLECTION_GENERAL_PROGRESS}
+	case "org2msp":
+		return []string{PRIVATE_COLLECTION_MTR_MFR1, PRIVATE_COLLECTION_MTR_MFR2, PRIVATE_COLLECTION_GENERAL_PROGRESS}
+	case "org3msp", "org4msp":
+		return []string{mtrCollection(mspId), PRIVATE_COLLECTION_GENERAL_PROGRESS}
+	}
+	return []string{}
+}
+
+/*
+	Method: heatNumberFromMtr
+	Returns the heat number recorded on a material certificate
+*/
+func heatNumberFromMtr(materialCert MaterialCertificate) string {
+	for _, detail := range materialCert.Data {
+		name := str.ToLower(str.Replace(detail.Name, " ", "", -1))
+		if name == "heatnumber" || name == "heat" {
+			return detail.Value
+		}
+	}
+	return ""
+}
+
+/*
+	Method: recordShipmentGenealogy
+	Links an order request to the shipment carrying it and the shipment to the PO line it delivers
+*/
+func recordShipmentGenealogy(stub shim.ChaincodeStubInterface, poId string, itemKey string, fulfilledBy string, shippingRequestNumber int64, iotTrackingCode string, timeStamp int64) {
+	shipmentId := strconv.FormatInt(shippingRequestNumber, 10)
+	recordGenealogyEdge(stub, PRIVATE_COLLECTION_GENERAL_PROGRESS, GenealogyEdge{FromType: GENEALOGY_NODE_ORDER_REQUEST, FromId: orderRequestNodeId(itemKey, fulfilledBy), ToType: GENEALOGY_NODE_SHIPMENT, ToId: shipmentId, PoId: poId, IotTrackingCode: iotTrackingCode, TimeStamp: timeStamp})
+	recordGenealogyEdge(stub, PRIVATE_COLLECTION_GENERAL_PROGRESS, GenealogyEdge{FromType: GENEALOGY_NODE_SHIPMENT, FromId: shipmentId, ToType: GENEALOGY_NODE_PO_LINE, ToId: itemKey, PoId: poId, IotTrackingCode: iotTrackingCode, TimeStamp: timeStamp})
+}
+
+func orderRequestNodeId(itemKey string, fulfilledBy string) string {
+	return itemKey + "|" + fulfilledBy
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_INSPECTION_ASSIGNMENT = "inspectionAssignment"
+	DOC_TYPE_ATTESTATION           = "attestation"
+	INSPECTION_TARGET_MTR          = "mtr"
+	INSPECTION_TARGET_SHIPPING     = "shippingRequest"
+	ATTESTATION_WITNESSED_TEST     = "witnessed-test"
+	ATTESTATION_VISUAL_INSPECTION  = "visual-inspection"
+	ATTESTATION_COATING_CHECK      = "coating-check"
+	ATTESTATION_RESULT_PASS        = "pass"
+	ATTESTATION_RESULT_FAIL        = "fail"
+	STATUS_INSPECTION_ASSIGNED     = "assigned"
+	STATUS_INSPECTION_COMPLETED    = "completed"
+)
+
+/*
+	Method: assignInspection
+	Executed when the customer or distributor assigns a certificate or shipping request to the inspector.
+	The assignment carries a copy of only the data needed for the inspection.
+*/
+func (s *SmartContract) assignInspection(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. assignment 2. timeStamp")
+	}
+	assignment := InspectionAssignment{}
+	err := json.Unmarshal([]byte(args[0]), &assignment)
+	if err != nil {
+		return shim.Error("Unable to parse assignment data provided - " + args[0])
+	}
+	assignment.AssignedTimeStamp, err = strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[1] + " Expecting a number.")
+	}
+	if assignment.Id == "" || assignment.TargetId == "" {
+		return shim.Error("id and targetId are required.")
+	}
+	existing, _ := stub.GetPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, assignment.Id))
+	if existing != nil {
+		return shim.Error("Inspection assignment already exists - " + assignment.Id)
+	}
+	for i, attestationType := range assignment.AttestationTypes {
+		assignment.AttestationTypes[i] = str.ToLower(attestationType)
+		if !validAttestationType(assignment.AttestationTypes[i]) {
+			return shim.Error("Unexpected attestation type - " + attestationType)
+		}
+	}
+	assignment.LineNumbers = make([]int, 0)
+	switch assignment.TargetType {
+	case INSPECTION_TARGET_MTR:
+		materialCert, found := inspectionCertificate(stub, assignment.TargetId)
+		if !found {
+			return shim.Error("Material certificate not found - " + assignment.TargetId)
+		}
+		assignment.Mtr = &InspectionMtr{
+			TrackingId:     materialCert.TrackingId,
+			Revision:       materialCert.Revision,
+			MaterialGroup:  materialCert.MaterialGroup,
+			Spec:           materialCert.Spec,
+			HeatNumber:     heatNumberFromMtr(materialCert),
+			DocumentSha256: materialCert.Document.Sha256,
+		}
+		if assignment.PoId != "" {
+			links, _ := getMtrLinks(stub, assignment.PoId, "")
+			for _, link := range links {
+				if link.TrackingId == materialCert.TrackingId {
+					assignment.LineNumbers = append(assignment.LineNumbers, link.LineNumber)
+				}
+			}
+		}
+	case INSPECTION_TARGET_SHIPPING:
+		if assignment.PoId == "" {
+			return shim.Error("poId is required to inspect a shipping request.")
+		}
+		shippingRequestNumber, err := strconv.ParseInt(assignment.TargetId, 10, 64)
+		if err != nil {
+			return shim.Error("Unable to parse shippingRequestNumber provided - " + assignment.TargetId + " Expecting an int64 number.")
+		}
+		shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+		if err != nil || !found || shippingRequest.PoId != assignment.PoId {
+			return shim.Error("Shipping Request " + assignment.TargetId + " not found for - " + assignment.PoId)
+		}
+		assignment.ShippingLines = shippingRequest.LineItems
+		for _, shipLineItem := range shippingRequest.LineItems {
+			assignment.LineNumbers = append(assignment.LineNumbers, shipLineItem.LineNumber)
+		}
+	default:
+		return shim.Error("Unexpected target type - " + assignment.TargetType + ". Expecting mtr or shippingRequest")
+	}
+	assignment.ObjectType = DOC_TYPE_INSPECTION_ASSIGNMENT
+	assignment.Inspector = organizationMap["org8msp"]
+	assignment.Status = STATUS_INSPECTION_ASSIGNED
+	assignment.Attestations = make([]string, 0)
+	assignment.SharedAttestations = make([]string, 0)
+	assignment.AssignedBy = organizationMap[currentMspId]
+	assignmentBytes, _ := json.Marshal(assignment)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, assignment.Id), assignmentBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(assignmentBytes)
+}
+
+/*
+	Method: recordAttestation
+	Executed when the inspector attests to a witnessed test, visual inspection or coating check of an assignment.
+	The attestation records the identity that signed the transaction and the hash of its content.
+	The inspector has no access to the shared progress, so an event tells the customer or distributor
+	to copy the attestation into the progress of the line items it covers.
+*/
+func (s *SmartContract) recordAttestation(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. attestation 2. timeStamp")
+	}
+	attestation := Attestation{}
+	err := json.Unmarshal([]byte(args[0]), &attestation)
+	if err != nil {
+		return shim.Error("Unable to parse attestation data provided - " + args[0])
+	}
+	attestation.TimeStamp, err = strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[1] + " Expecting a number.")
+	}
+	assignmentBytes, _ := stub.GetPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, attestation.AssignmentId))
+	if assignmentBytes == nil {
+		return shim.Error("Inspection assignment not found - " + attestation.AssignmentId)
+	}
+	assignment := InspectionAssignment{}
+	json.Unmarshal(assignmentBytes, &assignment)
+	attestation.Type = str.ToLower(attestation.Type)
+	attestation.Result = str.ToLower(attestation.Result)
+	if !validAttestationType(attestation.Type) || (len(assignment.AttestationTypes) > 0 && !containsFold(assignment.AttestationTypes, attestation.Type)) {
+		return shim.Error("Attestation type " + attestation.Type + " is not part of assignment " + assignment.Id)
+	}
+	if attestation.Result != ATTESTATION_RESULT_PASS && attestation.Result != ATTESTATION_RESULT_FAIL {
+		return shim.Error("Unexpected result - " + attestation.Result + ". Expecting pass or fail")
+	}
+	attestation.ObjectType = DOC_TYPE_ATTESTATION
+	attestation.Id = stub.GetTxID()
+	attestation.TargetType = assignment.TargetType
+	attestation.TargetId = assignment.TargetId
+	attestation.PoId = assignment.PoId
+	attestation.LineNumbers = assignment.LineNumbers
+	attestation.Inspector = organizationMap[currentMspId]
+	attestation.Signer, err = creatorIdentity(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	attestation.ContentSha256 = ""
+	contentBytes, _ := json.Marshal(attestation)
+	contentHash := sha256.Sum256(contentBytes)
+	attestation.ContentSha256 = hex.EncodeToString(contentHash[:])
+	attestationKey, err := stub.CreateCompositeKey(DOC_TYPE_ATTESTATION, []string{attestation.TargetType, attestation.TargetId, attestation.Type, attestation.Id})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	attestationBytes, _ := json.Marshal(attestation)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_INSPECTION, attestationKey, attestationBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	assignment.Attestations = append(assignment.Attestations, attestation.Id)
+	if assignment.Status != STATUS_INSPECTION_COMPLETED && attestationsCovered(stub, assignment, attestation.Type) {
+		assignment.Status = STATUS_INSPECTION_COMPLETED
+	}
+	assignmentBytes, _ = json.Marshal(assignment)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, assignment.Id), assignmentBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setAttestationEvent(stub, assignment, attestation)
+	return shim.Success(attestationBytes)
+}
+
+/*
+	Method: shareAttestationProgress
+	Executed by the customer or distributor to copy the attestations of an assignment into the
+	progress of the line items they cover. Only the type, result and inspector are shared.
+*/
+func (s *SmartContract) shareAttestationProgress(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. assignmentId")
+	}
+	assignmentBytes, _ := stub.GetPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, args[0]))
+	if assignmentBytes == nil {
+		return shim.Error("Inspection assignment not found - " + args[0])
+	}
+	assignment := InspectionAssignment{}
+	json.Unmarshal(assignmentBytes, &assignment)
+	if assignment.PoId == "" || len(assignment.LineNumbers) == 0 {
+		return shim.Error("Inspection assignment " + assignment.Id + " does not cover any purchase order line")
+	}
+	attestations, err := getAttestations(stub, assignment.TargetType, assignment.TargetId, "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	sort.Slice(attestations, func(i, j int) bool { return attestations[i].TimeStamp < attestations[j].TimeStamp })
+	sharedItemsMap := make(map[int]LineItem)
+	for _, lineNumber := range assignment.LineNumbers {
+		sharedItemsMap[lineNumber] = LineItem{LineNumber: lineNumber}
+	}
+	for _, attestation := range attestations {
+		if attestation.AssignmentId != assignment.Id || containsFold(assignment.SharedAttestations, attestation.Id) {
+			continue
+		}
+		// Add progress to shared table
+		updateSharedProgressRecord(stub, assignment.PoId, sharedItemsMap, ItemStatus{Owner: attestation.Inspector, Status: attestation.Type + "-" + attestation.Result, TimeStamp: attestation.TimeStamp}, MODE_ATTESTATION)
+		assignment.SharedAttestations = append(assignment.SharedAttestations, attestation.Id)
+	}
+	assignmentBytes, _ = json.Marshal(assignment)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_INSPECTION, inspectionAssignmentKey(stub, assignment.Id), assignmentBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(assignmentBytes)
+}
+
+/*
+	Method: queryInspectionAssignments
+	Returns inspection assignments, optionally only those of a status
+*/
+func (s *SmartContract) queryInspectionAssignments(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	status := ""
+	if len(args) > 0 {
+		status = args[0]
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_INSPECTION, DOC_TYPE_INSPECTION_ASSIGNMENT, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	assignments := make([]InspectionAssignment, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		assignment := InspectionAssignment{}
+		json.Unmarshal(queryResponse.Value, &assignment)
+		if status != "" && assignment.Status != status {
+			continue
+		}
+		assignments = append(assignments, assignment)
+	}
+	assignmentBytes, _ := json.Marshal(assignments)
+	return shim.Success(assignmentBytes)
+}
+
+/*
+	Method: queryAttestations
+	Returns the attestations recorded against a certificate or shipping request
+*/
+func (s *SmartContract) queryAttestations(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. targetType 2. targetId")
+	}
+	attestations, err := getAttestations(stub, args[0], args[1], "")
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	attestationBytes, _ := json.Marshal(attestations)
+	return shim.Success(attestationBytes)
+}
+
+/*
+	Method: checkAttestations
+	Returns an error unless the latest attestation of every required type for the target is a pass
+*/
+func checkAttestations(stub shim.ChaincodeStubInterface, targetType string, targetId string, requiredTypes []string) error {
+	for _, requiredType := range requiredTypes {
+		attestations, err := getAttestations(stub, targetType, targetId, str.ToLower(requiredType))
+		if err != nil {
+			return err
+		}
+		latest := Attestation{}
+		for _, attestation := range attestations {
+			if attestation.TimeStamp >= latest.TimeStamp {
+				latest = attestation
+			}
+		}
+		if latest.Result != ATTESTATION_RESULT_PASS {
+			return fmt.Errorf("%s %s requires a passing %s attestation", targetType, targetId, requiredType)
+		}
+	}
+	return nil
+}
+
+/*
+	Method: checkShipmentAttestations
+	Applies the shipment attestations required by the template of a shipping line's material group
+*/
+func checkShipmentAttestations(stub shim.ChaincodeStubInterface, shipLineItem ShippingLineItem) error {
+	material, found, _ := getMaterial(stub, shipLineItem.MaterialId)
+	if !found {
+		return nil
+	}
+	template, found := getMtrTemplate(stub, material.MaterialGroup)
+	if !found {
+		return nil
+	}
+	return checkAttestations(stub, INSPECTION_TARGET_SHIPPING, strconv.FormatInt(shipLineItem.ShippingRequestNumber, 10), template.ShipmentAttestations)
+}
+
+func getAttestations(stub shim.ChaincodeStubInterface, targetType string, targetId string, attestationType string) ([]Attestation, error) {
+	keys := []string{targetType, targetId}
+	if attestationType != "" {
+		keys = append(keys, attestationType)
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_INSPECTION, DOC_TYPE_ATTESTATION, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	attestations := make([]Attestation, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		attestation := Attestation{}
+		json.Unmarshal(queryResponse.Value, &attestation)
+		attestations = append(attestations, attestation)
+	}
+	return attestations, nil
+}
+
+/*
+	Method: attestationsCovered
+	Whether every attestation type of an assignment has been recorded, counting the one being written
+*/
+func attestationsCovered(stub shim.ChaincodeStubInterface, assignment InspectionAssignment, recordedType string) bool {
+	for _, attestationType := range assignment.AttestationTypes {
+		if attestationType == recordedType {
+			continue
+		}
+		attestations, _ := getAttestations(stub, assignment.TargetType, assignment.TargetId, attestationType)
+		covered := false
+		for _, attestation := range attestations {
+			covered = covered || attestation.AssignmentId == assignment.Id
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+	Method: inspectionCertificate
+	Reads the certificate to inspect, the customer only has access to released copies
+*/
+func inspectionCertificate(stub shim.ChaincodeStubInterface, trackingId string) (MaterialCertificate, bool) {
+	if currentMspId != "org1msp" {
+		materialCert, _, found := getMaterialCertificate(stub, trackingId)
+		return materialCert, found
+	}
+	return releasedCertificate(stub, trackingId)
+}
+
+/*
+	Method: setAttestationEvent
+	Emits an event for a recorded attestation, naming the assignment to share into the line progress
+*/
+func setAttestationEvent(stub shim.ChaincodeStubInterface, assignment InspectionAssignment, attestation Attestation) {
+	var event = CustomEvent{Type: "attestationrecorded", Description: "Attestation Recorded", Status: attestation.Type + "-" + attestation.Result, Id: assignment.Id, Custodian: attestation.Inspector, TimeStamp: attestation.TimeStamp}
+	eventBytes, err := json.Marshal(&event)
+	if err != nil {
+		fmt.Println("unable to marshal event ", err)
+	}
+	err = stub.SetEvent(event.Type, eventBytes)
+	if err != nil {
+		fmt.Println("Could not set event for attestation ", err)
+	} else {
+		logger.Infof("Event set - type: %s description: %s", event.Type, event.Description)
+	}
+}
+
+func inspectionAssignmentKey(stub shim.ChaincodeStubInterface, id string) string {
+	key, _ := stub.CreateCompositeKey(DOC_TYPE_INSPECTION_ASSIGNMENT, []string{id})
+	return key
+}
+
+func validAttestationType(attestationType string) bool {
+	switch attestationType {
+	case ATTESTATION_WITNESSED_TEST, ATTESTATION_VISUAL_INSPECTION, ATTESTATION_COATING_CHECK:
+		return true
+	}
+	return false
+}
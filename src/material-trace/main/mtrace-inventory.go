@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_INVENTORY            = "inventory"
+	INVENTORY_TRANSACTION_RECEIPT = "receipt"
+	INVENTORY_TRANSACTION_ADJUST  = "adjustment"
+	INVENTORY_TRANSACTION_RESERVE = "reservation"
+	INVENTORY_TRANSACTION_ISSUE   = "issue"
+	INVENTORY_DEFAULT_LOCATION    = "main"
+)
+
+/*
+	Method: receiveInventory
+	Executed when the distributor receives stock into a location
+*/
+func (s *SmartContract) receiveInventory(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. inventory receipt")
+	}
+	transaction := InventoryTransaction{}
+	err := json.Unmarshal([]byte(args[0]), &transaction)
+	if err != nil {
+		return shim.Error("Unable to parse inventory receipt provided - " + args[0])
+	}
+	if transaction.Quantity <= 0 {
+		return shim.Error("Receipt quantity must be greater than zero.")
+	}
+	transaction.Type = INVENTORY_TRANSACTION_RECEIPT
+	return s.postInventoryTransaction(stub, transaction)
+}
+
+/*
+	Method: adjustInventory
+	Executed when the distributor corrects the quantity on hand, e.g. after a cycle count.
+	Quantity is the signed difference and a reason is required.
+*/
+func (s *SmartContract) adjustInventory(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. inventory adjustment")
+	}
+	transaction := InventoryTransaction{}
+	err := json.Unmarshal([]byte(args[0]), &transaction)
+	if err != nil {
+		return shim.Error("Unable to parse inventory adjustment provided - " + args[0])
+	}
+	if transaction.Quantity == 0 || len(transaction.Reason) == 0 {
+		return shim.Error("A non zero quantity and a reason are required for an adjustment.")
+	}
+	transaction.Type = INVENTORY_TRANSACTION_ADJUST
+	return s.postInventoryTransaction(stub, transaction)
+}
+
+func (s *SmartContract) postInventoryTransaction(stub shim.ChaincodeStubInterface, transaction InventoryTransaction) sc.Response {
+	if transaction.MaterialId == "" {
+		return shim.Error("materialId is required.")
+	}
+	if transaction.Location == "" {
+		transaction.Location = INVENTORY_DEFAULT_LOCATION
+	}
+	// stock is held in the base unit of measure of the material
+	if transaction.UnitOfMeasure != "" {
+		quantity, unit, _ := baseQuantity(stub, transaction.MaterialId, float64(transaction.Quantity), transaction.UnitOfMeasure)
+		transaction.Quantity = int(math.Round(quantity))
+		transaction.UnitOfMeasure = unit
+	}
+	cache := make(map[string]*InventoryRecord)
+	record, err := getInventoryRecord(stub, cache, transaction.MaterialId, transaction.Location)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if record.OnHand+transaction.Quantity < record.Reserved {
+		return shim.Error(fmt.Sprintf("Adjustment would leave %d on hand for %s at %s while %d is reserved", record.OnHand+transaction.Quantity, record.MaterialId, record.Location, record.Reserved))
+	}
+	if transaction.Description != "" {
+		record.Description = transaction.Description
+	}
+	if transaction.UnitOfMeasure != "" {
+		record.UnitOfMeasure = transaction.UnitOfMeasure
+	}
+	record.OnHand += transaction.Quantity
+	record.Transactions = append(record.Transactions, transaction)
+	err = commitInventory(stub, cache)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordBytes, _ := json.Marshal(record)
+	return shim.Success(recordBytes)
+}
+
+/*
+	Method: queryInventoryOnHand
+	Returns the inventory records of the distributor, optionally for a single material.
+	Data displayed in the inventory manager screen
+*/
+func (s *SmartContract) queryInventoryOnHand(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	keys := []string{}
+	if len(args) > 0 && args[0] != "" {
+		keys = []string{args[0]}
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY, DOC_TYPE_INVENTORY, keys)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	records := make([]InventoryRecord, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		record := InventoryRecord{}
+		json.Unmarshal(queryResponse.Value, &record)
+		records = append(records, record)
+	}
+	recordBytes, _ := json.Marshal(records)
+	return shim.Success(recordBytes)
+}
+
+/*
+	Method: availableInventory
+	Returns the unreserved quantity of a material across all locations in the unit of measure
+	of a line item. Stock is held in the base unit, so partial line units are not available.
+*/
+func availableInventory(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord, materialId string, unitOfMeasure string) int {
+	available := 0
+	for _, record := range getInventoryRecordsForMaterial(stub, cache, materialId) {
+		available += record.OnHand - record.Reserved
+	}
+	if available <= 0 {
+		return available
+	}
+	_, _, factor := baseQuantity(stub, materialId, 1, unitOfMeasure)
+	return int(math.Floor(roundUnits(float64(available) / factor)))
+}
+
+/*
+	Method: inventoryUnits
+	Converts a line item quantity to the base unit of measure the stock is held in, rounding up
+*/
+func inventoryUnits(stub shim.ChaincodeStubInterface, materialId string, quantity int, unitOfMeasure string) int {
+	base, _, _ := baseQuantity(stub, materialId, float64(quantity), unitOfMeasure)
+	return int(math.Ceil(base))
+}
+
+/*
+	Method: reserveInventory
+	Reserves stock of a material for a PO line item, taking from locations in order.
+	The line quantity is converted to the base unit, so reservations are in base units.
+*/
+func reserveInventory(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord, materialId string, quantity int, unitOfMeasure string, itemKey string, timeStamp int64) error {
+	if availableInventory(stub, cache, materialId, unitOfMeasure) < quantity {
+		return fmt.Errorf("insufficient inventory for material %s, %d %s available", materialId, availableInventory(stub, cache, materialId, unitOfMeasure), unitOfMeasure)
+	}
+	remaining := inventoryUnits(stub, materialId, quantity, unitOfMeasure)
+	for _, record := range getInventoryRecordsForMaterial(stub, cache, materialId) {
+		if remaining == 0 {
+			break
+		}
+		reserved := record.OnHand - record.Reserved
+		if reserved <= 0 {
+			continue
+		}
+		if reserved > remaining {
+			reserved = remaining
+		}
+		record.Reserved += reserved
+		record.Reservations = append(record.Reservations, InventoryReservation{ItemKey: itemKey, Quantity: reserved, TimeStamp: timeStamp})
+		record.Transactions = append(record.Transactions, InventoryTransaction{Type: INVENTORY_TRANSACTION_RESERVE, MaterialId: materialId, Location: record.Location, Quantity: reserved, Reference: itemKey, TimeStamp: timeStamp})
+		remaining -= reserved
+	}
+	return nil
+}
+
+/*
+	Method: issueReservedInventory
+	Decrements the stock reserved for a PO line item when it ships to the customer.
+	Returns the issued quantity in the base unit of measure.
+*/
+func issueReservedInventory(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord, materialId string, itemKey string, timeStamp int64) int {
+	issued := 0
+	for _, record := range getInventoryRecordsForMaterial(stub, cache, materialId) {
+		reservations := make([]InventoryReservation, 0)
+		for _, reservation := range record.Reservations {
+			if reservation.ItemKey != itemKey {
+				reservations = append(reservations, reservation)
+				continue
+			}
+			record.OnHand -= reservation.Quantity
+			record.Reserved -= reservation.Quantity
+			record.Transactions = append(record.Transactions, InventoryTransaction{Type: INVENTORY_TRANSACTION_ISSUE, MaterialId: materialId, Location: record.Location, Quantity: -reservation.Quantity, Reference: itemKey, TimeStamp: timeStamp})
+			issued += reservation.Quantity
+		}
+		record.Reservations = reservations
+	}
+	return issued
+}
+
+/*
+	Method: getInventoryRecord
+	Returns the record for a material and location, creating an empty one if it does not exist yet
+*/
+func getInventoryRecord(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord, materialId string, location string) (*InventoryRecord, error) {
+	key, err := stub.CreateCompositeKey(DOC_TYPE_INVENTORY, []string{materialId, location})
+	if err != nil {
+		return nil, err
+	}
+	if record, found := cache[key]; found {
+		return record, nil
+	}
+	record := &InventoryRecord{ObjectType: DOC_TYPE_INVENTORY, MaterialId: materialId, Location: location}
+	recordResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY, key)
+	if err != nil {
+		return nil, err
+	}
+	if recordResponse != nil {
+		json.Unmarshal(recordResponse, record)
+	}
+	cache[key] = record
+	return record, nil
+}
+
+/*
+	Method: getInventoryRecordsForMaterial
+	Loads every location holding a material into the cache and returns them ordered by key.
+	Reads within a transaction don't see its own writes, so all changes go through the cache.
+*/
+func getInventoryRecordsForMaterial(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord, materialId string) []*InventoryRecord {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY, DOC_TYPE_INVENTORY, []string{materialId})
+	if err != nil {
+		logger.Infof("Unable to get %s data for material: %s ", PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY, materialId)
+	} else {
+		defer resultsIterator.Close()
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				break
+			}
+			if _, found := cache[queryResponse.Key]; found {
+				continue
+			}
+			record := &InventoryRecord{}
+			json.Unmarshal(queryResponse.Value, record)
+			cache[queryResponse.Key] = record
+		}
+	}
+	keys := make([]string, 0)
+	for key, record := range cache {
+		if record.MaterialId == materialId {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	records := make([]*InventoryRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, cache[key])
+	}
+	return records
+}
+
+/*
+	Method: commitInventory
+	Writes every cached inventory record back to the distributor inventory collection
+*/
+func commitInventory(stub shim.ChaincodeStubInterface, cache map[string]*InventoryRecord) error {
+	keys := make([]string, 0, len(cache))
+	for key := range cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		record := cache[key]
+		record.Available = record.OnHand - record.Reserved
+		recordBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		err = stub.PutPrivateData(PRIVATE_COLLECTION_DISTRIBUTOR_INVENTORY, key, recordBytes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_CONSOLIDATED_LOAD = "consolidatedLoad"
+)
+
+/*
+	Method: consolidateLoad
+	Executed by the shipper to put shipping requests of several POs on one truck under one IoT tracker.
+	Requests are consolidated before they are tendered so the load goes to a single carrier.
+	IoT readings of the load tracker are fanned out to every PO on the load. The load keeps the
+	tracking code each PO's lines are recorded under, so each PO can only be on a load under one code.
+*/
+func (s *SmartContract) consolidateLoad(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. load 2. timeStamp")
+	}
+	load := ConsolidatedLoad{}
+	err := json.Unmarshal([]byte(args[0]), &load)
+	if err != nil {
+		return shim.Error("Unable to parse load data provided - " + args[0])
+	}
+	timeStamp, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[1] + " Expecting a number.")
+	}
+	if load.LoadId == "" || load.IotTrackingCode == "" || len(load.ShippingRequestNumbers) == 0 {
+		return shim.Error("loadId, iotTrackingCode and shippingRequestNumbers are required.")
+	}
+	if _, found, _ := getConsolidatedLoad(stub, load.LoadId); found {
+		return shim.Error("Load already exists - " + load.LoadId)
+	}
+	trackingCodeByPoId := make(map[string]string)
+	poIds := make(map[string]bool)
+	projectIds := make(map[string]bool)
+	shippingRequests := make([]ShippingRequest, 0)
+	for _, shippingRequestNumber := range load.ShippingRequestNumbers {
+		number := strconv.FormatInt(shippingRequestNumber, 10)
+		shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if !found {
+			return shim.Error("Shipping Request not found - " + number)
+		}
+		if shippingRequest.LoadId != "" {
+			return shim.Error("Shipping Request " + number + " is already on load " + shippingRequest.LoadId)
+		}
+		if shippingRequest.Status != STATUS_OPEN || len(shippingRequest.TenderedTo) > 0 {
+			return shim.Error("Shipping Request " + number + " is already tendered or moving")
+		}
+		if shippingRequest.IotTrackingCode == "" {
+			return shim.Error("Shipping Request " + number + " has no tracking code")
+		}
+		if trackingCode, found := trackingCodeByPoId[shippingRequest.PoId]; found && trackingCode != shippingRequest.IotTrackingCode {
+			return shim.Error("PO " + shippingRequest.PoId + " is on the load under tracking codes " + trackingCode + " and " + shippingRequest.IotTrackingCode)
+		}
+		trackingCodeByPoId[shippingRequest.PoId] = shippingRequest.IotTrackingCode
+		poIds[shippingRequest.PoId] = true
+		if value, err := stub.GetState(shippingRequest.PoId); err == nil && value != nil {
+			po := PurchaseOrder{}
+			json.Unmarshal(value, &po)
+			if po.ProjectId != "" {
+				projectIds[po.ProjectId] = true
+			}
+		}
+		shippingRequests = append(shippingRequests, shippingRequest)
+	}
+	for _, shippingRequest := range shippingRequests {
+		shippingRequest.LoadId = load.LoadId
+		if err := commitShippingRequest(stub, shippingRequest); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	load.ObjectType = DOC_TYPE_CONSOLIDATED_LOAD
+	load.PoIds = sortedKeys(poIds)
+	load.ProjectIds = sortedKeys(projectIds)
+	load.TrackingCodes = trackingCodeByPoId
+	load.ConsolidatedBy = organizationMap[currentMspId]
+	load.Status = STATUS_OPEN
+	load.TimeConsolidated = timeStamp
+	load.ShippingRequests = nil
+	if err := commitConsolidatedLoad(stub, load); err != nil {
+		return shim.Error(err.Error())
+	}
+	loadBytes, _ := json.Marshal(load)
+	return shim.Success(loadBytes)
+}
+
+/*
+	Method: queryConsolidatedLoad
+	Returns a load with its shipping requests
+*/
+func (s *SmartContract) queryConsolidatedLoad(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. loadId")
+	}
+	load, found, err := getConsolidatedLoad(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Load not found - " + args[0])
+	}
+	loadBytes, _ := json.Marshal(load)
+	return shim.Success(loadBytes)
+}
+
+/*
+	Method: verifyLoadReceived
+	Executed by the customer to verify everything delivered on a load, one PO at a time
+*/
+func (s *SmartContract) verifyLoadReceived(stub shim.ChaincodeStubInterface, loadId string) sc.Response {
+	load, found, err := getConsolidatedLoad(stub, loadId)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Load not found - " + loadId)
+	}
+	goodReceipts := make([]GoodReceipt, 0)
+	for _, poId := range load.PoIds {
+		shippingRequestNumbers := make([]int64, 0)
+		for _, shippingRequest := range load.ShippingRequests {
+			if shippingRequest.PoId == poId {
+				shippingRequestNumbers = append(shippingRequestNumbers, shippingRequest.ShippingRequestNumber)
+			}
+		}
+		receipts, err := verifyReceivedItems(stub, poId, shippingRequestNumbers)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		goodReceipts = append(goodReceipts, receipts...)
+	}
+	rsBytes, _ := json.Marshal(goodReceipts)
+	return shim.Success(rsBytes)
+}
+
+/*
+	Method: loadTrackingTargets
+	Returns one shipping request per PO on a load, carrying the tracking code the load's tracker maps to for that PO.
+	Loads consolidated before the codes were kept on the load use the code of the shipping request.
+*/
+func loadTrackingTargets(load ConsolidatedLoad) []ShippingRequest {
+	targets := make([]ShippingRequest, 0)
+	for _, poId := range load.PoIds {
+		for _, shippingRequest := range load.ShippingRequests {
+			if shippingRequest.PoId == poId {
+				if trackingCode, found := load.TrackingCodes[poId]; found {
+					shippingRequest.IotTrackingCode = trackingCode
+				}
+				targets = append(targets, shippingRequest)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+/*
+	Method: checkLoadCarrier
+	A load is on one truck, so all its shipping requests go to the same carrier
+*/
+func checkLoadCarrier(stub shim.ChaincodeStubInterface, loadId string, carrier string) error {
+	load, found, err := getConsolidatedLoad(stub, loadId)
+	if err != nil || !found {
+		return err
+	}
+	for _, shippingRequest := range load.ShippingRequests {
+		if shippingRequest.AwardedTo != "" && shippingRequest.AwardedTo != carrier {
+			return fmt.Errorf("load %s is already awarded to %s", loadId, organizationMap[shippingRequest.AwardedTo])
+		}
+	}
+	return nil
+}
+
+func commitConsolidatedLoad(stub shim.ChaincodeStubInterface, load ConsolidatedLoad) error {
+	loadKey, err := stub.CreateCompositeKey(DOC_TYPE_CONSOLIDATED_LOAD, []string{load.LoadId})
+	if err != nil {
+		return err
+	}
+	loadBytes, err := json.Marshal(load)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(PRIVATE_COLLECTION_SHIPPING, loadKey, loadBytes)
+}
+
+/*
+	Reads a load with its shipping requests and works out its status from them
+*/
+func getConsolidatedLoad(stub shim.ChaincodeStubInterface, loadId string) (ConsolidatedLoad, bool, error) {
+	load := ConsolidatedLoad{}
+	loadKey, err := stub.CreateCompositeKey(DOC_TYPE_CONSOLIDATED_LOAD, []string{loadId})
+	if err != nil {
+		return load, false, err
+	}
+	loadBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_SHIPPING, loadKey)
+	if err != nil || loadBytes == nil {
+		return load, false, err
+	}
+	json.Unmarshal(loadBytes, &load)
+	combined := ShippingRequest{LineItems: make([]ShippingLineItem, 0)}
+	load.ShippingRequests = make([]ShippingRequest, 0)
+	for _, shippingRequestNumber := range load.ShippingRequestNumbers {
+		shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+		if err != nil {
+			return load, false, err
+		}
+		if found {
+			load.ShippingRequests = append(load.ShippingRequests, shippingRequest)
+			combined.LineItems = append(combined.LineItems, shippingRequest.LineItems...)
+		}
+	}
+	refreshShippingRequestStatus(&combined)
+	load.Status = combined.Status
+	return load, true, nil
+}
@@ -3,14 +3,21 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	sc "github.com/hyperledger/fabric/protos/peer"
 )
 
+const (
+	DOC_TYPE_SHIPPING_REQUEST    = "shippingRequest"
+	DOC_TYPE_SHIPPING_REQUEST_PO = "shippingRequestPo"
+)
+
 /*
 	Method: logisticsAcceptAndShipsToCustomer
-	Executed when logistics operator accepts a shipment request
+	Executed when a carrier picks up a load awarded to it. Only the carrier's copy of the shipping
+	request is updated, the shipper brings its own copy up to date on the shipmentaccepted event.
 */
 func (s *SmartContract) logisticsAcceptAndShipsToCustomer(stub shim.ChaincodeStubInterface, args []string) sc.Response {
 
@@ -23,8 +30,14 @@ func (s *SmartContract) logisticsAcceptAndShipsToCustomer(stub shim.ChaincodeStu
 		return shim.Error("Unable to parse lineItem data provided - " + args[1])
 	}
 	var lineitemToShipMap = make(map[int]LineItem)
+	lineNumbers := make([]int, 0)
 	for _, lineItem := range lineItemsToShip {
 		lineitemToShipMap[lineItem.LineNumber] = lineItem
+		lineNumbers = append(lineNumbers, lineItem.LineNumber)
+	}
+	// material under an open recall cannot move
+	if err := checkRecallHolds(stub, args[0], lineNumbers); err != nil {
+		return shim.Error(err.Error())
 	}
 	// progressStatus := ItemStatus{}
 	// err = json.Unmarshal([]byte(args[2]), &progressStatus)
@@ -33,44 +46,55 @@ func (s *SmartContract) logisticsAcceptAndShipsToCustomer(stub shim.ChaincodeStu
 	// }
 
 	poId := args[0]
-	shippingPd := ShippingPrivateDetails{}
-	shippingPrivateDataResponse, err1 := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
-	if err1 != nil {
-		return shim.Error("Shipping Request not found for - " + args[0])
+	carrier := organizationMap[currentMspId]
+	shippingRequests := make([]ShippingRequest, 0)
+	for _, collection := range carrierCollections(currentMspId) {
+		tenders, err := getShipmentTendersForPo(stub, collection, poId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		for _, tender := range tenders {
+			if tender.Status == STATUS_AWARDED {
+				shippingRequests = append(shippingRequests, tender.ShippingRequest)
+			}
+		}
+	}
+	if len(shippingRequests) == 0 {
+		return shim.Error("No shipment awarded to " + carrier + " for - " + poId)
 	}
 	event := ItemDeliveryEvent{}
-	event.ShippedLineItems = make([]ShippingLineItem, 1)
-	if shippingPrivateDataResponse != nil {
-		shippingItemCount := 0
-		json.Unmarshal(shippingPrivateDataResponse, &shippingPd)
-		for i, shipLineItem := range shippingPd.LineItems {
-			lineItem := lineitemToShipMap[shipLineItem.LineNumber]
-			if shipLineItem.LineNumber != lineItem.LineNumber {
+	event.ShippedLineItems = make([]ShippingLineItem, 0)
+	for r, shippingRequest := range shippingRequests {
+		matched := false
+		for i, shipLineItem := range shippingRequest.LineItems {
+			lineItem, found := lineitemToShipMap[shipLineItem.LineNumber]
+			if !found {
 				continue
 			}
+			matched = true
 			if shipLineItem.Status == STATUS_OPEN || shipLineItem.Status == "readyforshipment" {
-				shippingPd.LineItems[i].Status = STATUS_IN_TRANSIT
-				shippingPd.LineItems[i].TimeShipped = lineItem.TimeShipped
-			}
-			if shippingItemCount == 0 {
-				// shippingPd.LineItems[i].ProgressStatus = append(shippingPd.LineItems[i].ProgressStatus, progressStatus)
-				event.ShippedLineItems[0] = shippingPd.LineItems[i]
-			} else {
-				event.ShippedLineItems = append(event.ShippedLineItems, shippingPd.LineItems[i])
+				// loads of some material groups need an inspector sign off before pickup
+				if err := checkShipmentAttestations(stub, shipLineItem); err != nil {
+					return shim.Error(err.Error())
+				}
+				shippingRequests[r].LineItems[i].Status = STATUS_IN_TRANSIT
+				shippingRequests[r].LineItems[i].TimeShipped = lineItem.TimeShipped
+				if shippingRequests[r].TimeShipped == 0 {
+					shippingRequests[r].TimeShipped = lineItem.TimeShipped
+				}
 			}
-			shippingItemCount += 1
+			event.ShippedLineItems = append(event.ShippedLineItems, shippingRequests[r].LineItems[i])
+		}
+		if !matched {
+			continue
+		}
+		refreshShippingRequestStatus(&shippingRequests[r])
+		if err := commitTenderedShippingRequest(stub, currentMspId, shippingRequests[r]); err != nil {
+			logger.Error("Unable to update shipping request: ", err)
+			return shim.Error(err.Error())
 		}
 	}
-	shippingPd.ObjectType = PRIVATE_COLLECTION_LOGISTICS
-	shippingLineItemBytes, err := json.Marshal(shippingPd)
-	if err != nil {
-		logger.Error("Error json.Marshal(shippingPd): ", err)
-		return shim.Error(err.Error())
-	}
-	err = stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId, shippingLineItemBytes)
-	if err != nil {
-		logger.Error("Unable to update shipping lineitems: ", err)
-	}
+	shippingRequestBytes, _ := json.Marshal(shippingRequests)
 	// here trigger event notification
 	if len(event.ShippedLineItems) > 0 {
 		//emit event
@@ -90,20 +114,151 @@ func (s *SmartContract) logisticsAcceptAndShipsToCustomer(stub shim.ChaincodeStu
 	} else {
 		fmt.Println("len(event.ShippedLineItems) is less than 1")
 	}
-	return shim.Success(shippingLineItemBytes)
+	return shim.Success(shippingRequestBytes)
+
+}
+
+/*
+	Method: queryShippingRequest
+	Returns a shipping request by its number
+*/
+func (s *SmartContract) queryShippingRequest(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. shippingRequestNumber")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[0] + " Expecting an int64 number.")
+	}
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Shipping Request not found - " + args[0])
+	}
+	shippingRequestBytes, _ := json.Marshal(shippingRequest)
+	return shim.Success(shippingRequestBytes)
+}
 
+/*
+	Method: queryShippingRequestsForPo
+	Returns the shipping requests of a purchase order
+*/
+func (s *SmartContract) queryShippingRequestsForPo(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. poId")
+	}
+	shippingRequests, err := getShippingRequestsForPo(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	shippingRequestBytes, _ := json.Marshal(shippingRequests)
+	return shim.Success(shippingRequestBytes)
+}
+
+/*
+	Method: migrateShippingRequests
+	Moves the shipping records of a PO out of the logistics collection, which every carrier can read,
+	into the shipping collection. Both the former per PO record and shipping request documents
+	stored there before loads were tendered are moved.
+*/
+func (s *SmartContract) migrateShippingRequests(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. poId")
+	}
+	poId := args[0]
+	requestMap := make(map[int64]*ShippingRequest)
+	requestNumbers := make([]int64, 0)
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_LOGISTICS, DOC_TYPE_SHIPPING_REQUEST_PO, []string{poId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		requestKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPPING_REQUEST, []string{string(queryResponse.Value)})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		shippingRequestBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, requestKey)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if shippingRequestBytes != nil {
+			shippingRequest := ShippingRequest{}
+			json.Unmarshal(shippingRequestBytes, &shippingRequest)
+			requestMap[shippingRequest.ShippingRequestNumber] = &shippingRequest
+			requestNumbers = append(requestNumbers, shippingRequest.ShippingRequestNumber)
+		}
+		if err := stub.DelPrivateData(PRIVATE_COLLECTION_LOGISTICS, requestKey); err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := stub.DelPrivateData(PRIVATE_COLLECTION_LOGISTICS, queryResponse.Key); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	shippingPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if shippingPrivateDataResponse != nil {
+		shippingPd := ShippingPrivateDetails{}
+		json.Unmarshal(shippingPrivateDataResponse, &shippingPd)
+		for _, shipLineItem := range shippingPd.LineItems {
+			shippingRequest, found := requestMap[shipLineItem.ShippingRequestNumber]
+			if !found {
+				existing, found, err := getShippingRequest(stub, shipLineItem.ShippingRequestNumber)
+				if err != nil {
+					return shim.Error(err.Error())
+				}
+				if !found {
+					existing = newShippingRequest(poId, shipLineItem.ShippingRequestNumber, shipLineItem, Company{})
+				}
+				shippingRequest = &existing
+				requestMap[shipLineItem.ShippingRequestNumber] = shippingRequest
+				requestNumbers = append(requestNumbers, shipLineItem.ShippingRequestNumber)
+			}
+			shippingRequest.LineItems = append(shippingRequest.LineItems, shipLineItem)
+			if shipLineItem.TimeShipped > 0 && (shippingRequest.TimeShipped == 0 || shipLineItem.TimeShipped < shippingRequest.TimeShipped) {
+				shippingRequest.TimeShipped = shipLineItem.TimeShipped
+			}
+		}
+		err = stub.DelPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	if len(requestNumbers) == 0 {
+		return shim.Error("No logistics record to migrate for - " + poId)
+	}
+	migrated := make([]ShippingRequest, 0)
+	for _, shippingRequestNumber := range requestNumbers {
+		shippingRequest := requestMap[shippingRequestNumber]
+		refreshShippingRequestStatus(shippingRequest)
+		if err := commitShippingRequest(stub, *shippingRequest); err != nil {
+			return shim.Error(err.Error())
+		}
+		migrated = append(migrated, *shippingRequest)
+	}
+	migratedBytes, _ := json.Marshal(migrated)
+	return shim.Success(migratedBytes)
 }
 
 /*
 	Method: fillShippingLineItems
-	Utility method to fill line items for addition to logistics privvate collection
+	Utility method to fill a line item for addition to a shipping request
 */
-func fillShippingLineItems(poId string, shippingRequestNumber int64, lineItem LineItem, shippingRequestedBy string, hasExistingData bool, shippingItemCount int, shippingPd ShippingPrivateDetails, initialStatus []ItemStatus, progressStatus ItemStatus) ShippingPrivateDetails {
+func fillShippingLineItems(stub shim.ChaincodeStubInterface, poId string, shippingRequestNumber int64, lineItem LineItem, shippingRequestedBy string, initialStatus []ItemStatus, progressStatus ItemStatus) ShippingLineItem {
 
 	shipLineItem := ShippingLineItem{}
 	shipLineItem.PoId = poId
 	shipLineItem.PoNumber = lineItem.PoNumber
 	shipLineItem.LineNumber = lineItem.LineNumber
+	shipLineItem.ItemKey = lineItem.ItemKey
 	shipLineItem.RequestedBy = shippingRequestedBy
 	shipLineItem.ShipToLocation = lineItem.ShipToLocation
 	shipLineItem.IotTrackingCode = lineItem.IotTrackingCode // iotTrackingCode
@@ -118,12 +273,7 @@ func fillShippingLineItems(poId string, shippingRequestNumber int64, lineItem Li
 	shipLineItem.ProgressStatus = make([]ItemStatus, 2)
 	shipLineItem.ProgressStatus = initialStatus
 	shipLineItem.ProgressStatus = append(shipLineItem.ProgressStatus, progressStatus)
-	if shippingItemCount == 0 && !hasExistingData {
-		shippingPd.LineItems[shippingItemCount] = shipLineItem
-	} else {
-		shippingPd.LineItems = append(shippingPd.LineItems, shipLineItem)
-	}
-	return shippingPd
+	return normalizeShippingLineItem(stub, shipLineItem)
 }
 
 /*
@@ -132,34 +282,41 @@ func fillShippingLineItems(poId string, shippingRequestNumber int64, lineItem Li
 */
 func updateLogisticsDeliveryStatus(stub shim.ChaincodeStubInterface, poId string, event ItemDeliveryEvent) ItemDeliveryEvent {
 
-	poPrivateDataResponse, err2 := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId)
+	shippingRequests, err2 := getShippingRequestsForPo(stub, poId)
 	if err2 != nil {
 		// log error here
-		logger.Info("unable to find private data for: " + poId + " in collection: " + PRIVATE_COLLECTION_LOGISTICS + " error: " + err2.Error())
-	} else {
-		shippingPrivateData := ShippingPrivateDetails{}
-		json.Unmarshal(poPrivateDataResponse, &shippingPrivateData)
+		logger.Info("unable to find shipping requests for: " + poId + " in collection: " + PRIVATE_COLLECTION_SHIPPING + " error: " + err2.Error())
+		return event
+	}
+	for _, shippingRequest := range shippingRequests {
 		updatedCount := 0
-		for i, eachItem := range shippingPrivateData.LineItems {
+		for i, eachItem := range shippingRequest.LineItems {
 			if eachItem.IotTrackingCode == event.TrackingCode {
-				shippingPrivateData.LineItems[i].Status = event.Status
-				shippingPrivateData.LineItems[i].ProgressStatus = append(shippingPrivateData.LineItems[i].ProgressStatus, event.ProgressStatus)
-				if updatedCount == 0 {
-					event.ShippingRequestNumber = shippingPrivateData.LineItems[i].ShippingRequestNumber
+				shippingRequest.LineItems[i].Status = event.Status
+				shippingRequest.LineItems[i].ProgressStatus = append(shippingRequest.LineItems[i].ProgressStatus, event.ProgressStatus)
+				if event.ShippingRequestNumber == 0 {
+					event.ShippingRequestNumber = shippingRequest.ShippingRequestNumber
 				}
 				updatedCount += 1
 			}
 		}
 		if updatedCount > 0 {
-			sdLineItemBytes, err2 := json.Marshal(shippingPrivateData)
-			if err2 != nil {
-				logger.Info("unable to marshal private data for: " + poId + " in collection: " + PRIVATE_COLLECTION_LOGISTICS + " error: " + err2.Error())
-				//	return shim.Error(err.Error())
+			refreshShippingRequestStatus(&shippingRequest)
+			if shippingRequest.Status == STATUS_DELIVERED && shippingRequest.TimeDelivered == 0 {
+				shippingRequest.TimeDelivered = event.ProgressStatus.TimeStamp
 			}
-			err2 = stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId, sdLineItemBytes)
+			err2 = commitShippingRequest(stub, shippingRequest)
 			if err2 != nil {
 				// return shim.Error(err.Error())
-				logger.Info("unable to commit data for: " + poId + " in collection: " + PRIVATE_COLLECTION_LOGISTICS + " error: " + err2.Error())
+				logger.Info("unable to commit data for: " + poId + " in collection: " + PRIVATE_COLLECTION_SHIPPING + " error: " + err2.Error())
+			}
+			// outside the carrier's collection the distributor syncs the tender when notified of the delivery
+			if isCarrierCollectionMember(currentMspId, shippingRequest.AwardedTo, shippingRequest.RequestedBy) {
+				if err := syncAwardedTender(stub, shippingRequest); err != nil {
+					logger.Error("Unable to update tender: ", err)
+				}
+			} else if shippingRequest.AwardedTo != "" {
+				logger.Infof("tender of shipping request %d left for the distributor to sync", shippingRequest.ShippingRequestNumber)
 			}
 		}
 	}
@@ -168,21 +325,143 @@ func updateLogisticsDeliveryStatus(stub shim.ChaincodeStubInterface, poId string
 }
 
 /*
-	Method: commitShippingPrivateData
-	Utility method to commit data into logistics table
+	Method: newShippingRequest
+	Starts a shipping request from its first line
 */
-func commitShippingPrivateData(stub shim.ChaincodeStubInterface, poId string, shippingPd ShippingPrivateDetails) {
-	logger.Info("commitShippingPrivateData: about to record shipment in logistics table for org " + currentMspId)
-	shippingLineItemBytes, err1 := json.Marshal(shippingPd)
-	if err1 == nil {
-		err2 := stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, poId, shippingLineItemBytes)
-		if err2 != nil {
-			logger.Info("Could not commit shipping lineitems ", err2)
-		} else {
-			logger.Info("notifyShipmentToCustomer: commited? record shipment in logistics table for org " + currentMspId)
+func newShippingRequest(poId string, shippingRequestNumber int64, shipLineItem ShippingLineItem, shipFrom Company) ShippingRequest {
+	return ShippingRequest{
+		ObjectType:            DOC_TYPE_SHIPPING_REQUEST,
+		ShippingRequestNumber: shippingRequestNumber,
+		PoId:                  poId,
+		PoNumber:              shipLineItem.PoNumber,
+		RequestedBy:           shipLineItem.RequestedBy,
+		ShipFrom:              shipFrom,
+		ShipToLocation:        shipLineItem.ShipToLocation,
+		IotTrackingCode:       shipLineItem.IotTrackingCode,
+		Status:                STATUS_OPEN,
+		LineItems:             make([]ShippingLineItem, 0),
+		TimeRequested:         shipLineItem.TimeRequested,
+	}
+}
+
+/*
+	Method: startShippingRequest
+	Returns the shipping request to add lines to, a new one when the number is not used yet.
+	A shipping request number belongs to a single purchase order.
+*/
+func startShippingRequest(stub shim.ChaincodeStubInterface, poId string, shippingRequestNumber int64, requestedBy string, shipFrom Company) (ShippingRequest, error) {
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil {
+		return shippingRequest, err
+	}
+	if !found {
+		return newShippingRequest(poId, shippingRequestNumber, ShippingLineItem{RequestedBy: requestedBy}, shipFrom), nil
+	}
+	if shippingRequest.PoId != poId {
+		return shippingRequest, fmt.Errorf("shipping request %d belongs to PO %s", shippingRequestNumber, shippingRequest.PoId)
+	}
+	return shippingRequest, nil
+}
+
+/*
+	Method: addShippingLineItem
+	Adds a line to a shipping request, filling the request level details from its first line
+*/
+func addShippingLineItem(shippingRequest *ShippingRequest, shipLineItem ShippingLineItem) {
+	if len(shippingRequest.LineItems) == 0 {
+		shippingRequest.PoNumber = shipLineItem.PoNumber
+		shippingRequest.ShipToLocation = shipLineItem.ShipToLocation
+		shippingRequest.IotTrackingCode = shipLineItem.IotTrackingCode
+		shippingRequest.TimeRequested = shipLineItem.TimeRequested
+	}
+	shippingRequest.LineItems = append(shippingRequest.LineItems, shipLineItem)
+}
+
+/*
+	Method: refreshShippingRequestStatus
+	A request is delivered once all its lines are, and in transit once any line has left
+*/
+func refreshShippingRequestStatus(shippingRequest *ShippingRequest) {
+	delivered := len(shippingRequest.LineItems) > 0
+	moving := false
+	for _, shipLineItem := range shippingRequest.LineItems {
+		delivered = delivered && shipLineItem.Status == STATUS_DELIVERED
+		moving = moving || shipLineItem.Status == STATUS_IN_TRANSIT || shipLineItem.Status == STATUS_DELIVERED
+	}
+	switch {
+	case delivered:
+		shippingRequest.Status = STATUS_DELIVERED
+	case moving:
+		shippingRequest.Status = STATUS_IN_TRANSIT
+	default:
+		shippingRequest.Status = STATUS_OPEN
+	}
+}
+
+/*
+	Method: commitShippingRequest
+	Utility method to commit a shipping request into the shipping collection, indexed by PO
+*/
+func commitShippingRequest(stub shim.ChaincodeStubInterface, shippingRequest ShippingRequest) error {
+	logger.Info("commitShippingRequest: about to record shipment in logistics table for org " + currentMspId)
+	shippingRequest.ObjectType = DOC_TYPE_SHIPPING_REQUEST
+	shippingRequestBytes, err := json.Marshal(shippingRequest)
+	if err != nil {
+		return err
+	}
+	number := strconv.FormatInt(shippingRequest.ShippingRequestNumber, 10)
+	requestKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPPING_REQUEST, []string{number})
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_SHIPPING, requestKey, shippingRequestBytes)
+	if err != nil {
+		return err
+	}
+	poKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPPING_REQUEST_PO, []string{shippingRequest.PoId, number})
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(PRIVATE_COLLECTION_SHIPPING, poKey, []byte(number))
+}
+
+func getShippingRequest(stub shim.ChaincodeStubInterface, shippingRequestNumber int64) (ShippingRequest, bool, error) {
+	shippingRequest := ShippingRequest{}
+	requestKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPPING_REQUEST, []string{strconv.FormatInt(shippingRequestNumber, 10)})
+	if err != nil {
+		return shippingRequest, false, err
+	}
+	shippingRequestBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_SHIPPING, requestKey)
+	if err != nil || shippingRequestBytes == nil {
+		return shippingRequest, false, err
+	}
+	json.Unmarshal(shippingRequestBytes, &shippingRequest)
+	return shippingRequest, true, nil
+}
+
+func getShippingRequestsForPo(stub shim.ChaincodeStubInterface, poId string) ([]ShippingRequest, error) {
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_SHIPPING, DOC_TYPE_SHIPPING_REQUEST_PO, []string{poId})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	shippingRequests := make([]ShippingRequest, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		shippingRequestNumber, err := strconv.ParseInt(string(queryResponse.Value), 10, 64)
+		if err != nil {
+			continue
+		}
+		shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			shippingRequests = append(shippingRequests, shippingRequest)
 		}
-	} else {
-		logger.Info("Unable to marshal shipping private data ", err1)
-		fmt.Println("Unable to marshal shipping private data ", err1)
 	}
+	return shippingRequests, nil
 }
@@ -102,15 +102,16 @@ func (s *SmartContract) manufacturerAcknowledgeOrderRequest(stub shim.ChaincodeS
 
 /*
 	Method: updateManufacturerIncomingIOT
-	Executed when new IOT data is received and updates specific line items assigned to the manufacturer
+	Executed when new IOT data is received and updates specific line items assigned to the manufacturer.
+	Returns the delivery event to emit once the items reached their destination.
 */
-func updateManufacturerIncomingIOT(stub shim.ChaincodeStubInterface, privateCollection string, poId string, iotInput IotProperty, itemStatus ItemStatus, msgKey string) (ok bool) {
+func updateManufacturerIncomingIOT(stub shim.ChaincodeStubInterface, privateCollection string, poId string, iotInput IotProperty, itemStatus ItemStatus, msgKey string) (ok bool, delivery *ItemDeliveryEvent) {
 
 	poPrivateDataResponse, err1 := stub.GetPrivateData(privateCollection, poId)
-	if err1 != nil {
+	if err1 != nil || poPrivateDataResponse == nil {
 		// log poId not found
 		logger.Info("unable to find data for: " + poId + " in collection: " + privateCollection)
-		return false
+		return false, nil
 	}
 	itemPrivateData := LineItemCDPrivateDetails{}
 	json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
@@ -155,14 +156,14 @@ func updateManufacturerIncomingIOT(stub shim.ChaincodeStubInterface, privateColl
 	pdLineItemBytes, err := json.Marshal(itemPrivateData)
 	if err != nil {
 		logger.Info("unable to marshal private data for: " + poId + " in collection: " + privateCollection + " error: " + err.Error())
-		return false
+		return false, nil
 		//	return shim.Error(err.Error())
 	}
 	err = stub.PutPrivateData(privateCollection, poId, pdLineItemBytes)
 	if err != nil {
 		// return shim.Error(err.Error())
 		logger.Info("unable to commit data for: " + poId + " in collection: " + privateCollection + " error: " + err.Error())
-		return false
+		return false, nil
 	}
 	if emit_on_delivery {
 
@@ -171,17 +172,25 @@ func updateManufacturerIncomingIOT(stub shim.ChaincodeStubInterface, privateColl
 
 		var event = ItemDeliveryEvent{Type: msgKey, Status: STATUS_DELIVERED, SkipDistributor: false, PoId: poId, TrackingCode: iotInput.TrackingCode, ItemMap: itemMap, ProgressStatus: itemStatus}
 		event = updateLogisticsDeliveryStatus(stub, poId, event)
-		eventBytes, err := json.Marshal(&event)
-		if err != nil {
-			fmt.Println("unable to marshal event ", err)
-		}
-		err = stub.SetEvent(event.Type, eventBytes)
-		if err != nil {
-			fmt.Println("Could not set event for items delivered ", err)
-		} else {
-			logger.Infof("Items delivered event emitted - %s itemMap: %s", event, event.ItemMap)
-		}
+		return true, &event
 	}
-	return true
+	return true, nil
 
 }
+
+/*
+	Method: setItemDeliveryEvent
+	Emits the items delivered event
+*/
+func setItemDeliveryEvent(stub shim.ChaincodeStubInterface, event ItemDeliveryEvent) {
+	eventBytes, err := json.Marshal(&event)
+	if err != nil {
+		fmt.Println("unable to marshal event ", err)
+	}
+	err = stub.SetEvent(event.Type, eventBytes)
+	if err != nil {
+		fmt.Println("Could not set event for items delivered ", err)
+	} else {
+		logger.Infof("Items delivered event emitted - %s itemMap: %s", event, event.ItemMap)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_MTR_DISCLOSURE       = "mtrDisclosure"
+	DOC_TYPE_MTR_DISCLOSURE_AUDIT = "mtrDisclosureAudit"
+)
+
+/*
+	Method: discloseMtr
+	Executed when a manufacturer or the distributor releases stored certificates to the customer.
+	An exact copy of each certificate is written to the customer readable collection of its manufacturer
+	together with the on-chain hash and revision of the original at release, so the customer can check
+	the copy even after the original changes, and an audit entry is recorded.
+*/
+func (s *SmartContract) discloseMtr(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. trackingIds 2. poId 3. reason 4. timeStamp")
+	}
+	trackingIds := []string{}
+	err := json.Unmarshal([]byte(args[0]), &trackingIds)
+	if err != nil || len(trackingIds) == 0 {
+		return shim.Error("Unable to parse trackingIds provided - " + args[0])
+	}
+	poId := args[1]
+	reason := args[2]
+	releasedTimeStamp, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[3] + " Expecting a number.")
+	}
+	disclosures := make([]MtrDisclosure, 0)
+	for _, trackingId := range trackingIds {
+		materialCert, sourceCollection, found := getMaterialCertificate(stub, trackingId)
+		if !found {
+			return shim.Error("Material certificate not found - " + trackingId)
+		}
+		// the copy is the original exactly as stored, so it hashes to the on-chain hash at release
+		certificateBytes, err := stub.GetPrivateData(sourceCollection, materialCert.TrackingId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		sourceHash := sha256.Sum256(certificateBytes)
+		disclosureCollection := mtrDisclosureCollection(sourceCollection)
+		disclosure := MtrDisclosure{
+			ObjectType:        DOC_TYPE_MTR_DISCLOSURE,
+			TrackingId:        materialCert.TrackingId,
+			PoId:              poId,
+			SourceCollection:  sourceCollection,
+			Certificate:       certificateBytes,
+			SourceHash:        hex.EncodeToString(sourceHash[:]),
+			SourceRevision:    materialCert.Revision,
+			Reason:            reason,
+			ReleasedBy:        organizationMap[currentMspId],
+			ReleasedTimeStamp: releasedTimeStamp,
+		}
+		disclosureBytes, _ := json.Marshal(disclosure)
+		err = stub.PutPrivateData(disclosureCollection, materialCert.TrackingId, disclosureBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		audit := MtrDisclosureAudit{
+			ObjectType:        DOC_TYPE_MTR_DISCLOSURE_AUDIT,
+			TrackingId:        materialCert.TrackingId,
+			PoId:              poId,
+			TxId:              stub.GetTxID(),
+			ReleasedBy:        disclosure.ReleasedBy,
+			ReleasedMspId:     currentMspId,
+			Reason:            reason,
+			CertificateHash:   disclosure.SourceHash,
+			SourceHash:        disclosure.SourceHash,
+			SourceRevision:    disclosure.SourceRevision,
+			ReleasedTimeStamp: releasedTimeStamp,
+		}
+		auditKey, err := stub.CreateCompositeKey(DOC_TYPE_MTR_DISCLOSURE_AUDIT, []string{materialCert.TrackingId, audit.TxId})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		auditBytes, _ := json.Marshal(audit)
+		err = stub.PutPrivateData(disclosureCollection, auditKey, auditBytes)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		disclosures = append(disclosures, disclosure)
+	}
+	disclosureBytes, _ := json.Marshal(disclosures)
+	return shim.Success(disclosureBytes)
+}
+
+/*
+	Method: queryDisclosedMtrs
+	Returns the certificates released to the customer, optionally for a single purchase order
+*/
+func (s *SmartContract) queryDisclosedMtrs(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	poId := ""
+	if len(args) > 0 {
+		poId = args[0]
+	}
+	disclosures := make([]MtrDisclosure, 0)
+	for _, disclosureCollection := range mtrDisclosureCollections(currentMspId) {
+		resultsIterator, err := stub.GetPrivateDataByRange(disclosureCollection, "", "")
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return shim.Error(err.Error())
+			}
+			disclosure := MtrDisclosure{}
+			json.Unmarshal(queryResponse.Value, &disclosure)
+			if disclosure.ObjectType != DOC_TYPE_MTR_DISCLOSURE || (poId != "" && disclosure.PoId != poId) {
+				continue
+			}
+			disclosures = append(disclosures, disclosure)
+		}
+		resultsIterator.Close()
+	}
+	disclosureBytes, _ := json.Marshal(disclosures)
+	return shim.Success(disclosureBytes)
+}
+
+/*
+	Method: queryMtrDisclosureAudit
+	Returns who released a certificate to the customer and when
+*/
+func (s *SmartContract) queryMtrDisclosureAudit(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. trackingId")
+	}
+	audits := make([]MtrDisclosureAudit, 0)
+	for _, disclosureCollection := range mtrDisclosureCollections(currentMspId) {
+		resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(disclosureCollection, DOC_TYPE_MTR_DISCLOSURE_AUDIT, []string{args[0]})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return shim.Error(err.Error())
+			}
+			audit := MtrDisclosureAudit{}
+			json.Unmarshal(queryResponse.Value, &audit)
+			audits = append(audits, audit)
+		}
+		resultsIterator.Close()
+	}
+	auditBytes, _ := json.Marshal(audits)
+	return shim.Success(auditBytes)
+}
+
+/*
+	Method: verifyMtrDisclosure
+	Checks the released copy of a certificate against the hash the original had when it was released,
+	and reports separately whether the original in the manufacturer collection has changed since.
+	Linking the original to more order requests only adds to referencedBy and does not count as a change.
+*/
+func (s *SmartContract) verifyMtrDisclosure(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. trackingId")
+	}
+	for _, disclosureCollection := range mtrDisclosureCollections(currentMspId) {
+		disclosureBytes, err := stub.GetPrivateData(disclosureCollection, args[0])
+		if err != nil || disclosureBytes == nil {
+			continue
+		}
+		disclosure := MtrDisclosure{}
+		json.Unmarshal(disclosureBytes, &disclosure)
+		currentHash, err := stub.GetPrivateDataHash(disclosure.SourceCollection, disclosure.TrackingId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		releasedHash := disclosure.SourceHash
+		if releasedHash == "" {
+			// released before the hash of the original was recorded
+			if currentHash == nil {
+				return shim.Error(fmt.Sprintf("no private data hash found for key %s in collection %s", disclosure.TrackingId, disclosure.SourceCollection))
+			}
+			releasedHash = hex.EncodeToString(currentHash)
+		}
+		presentedHash := sha256.Sum256(disclosure.Certificate)
+		verification := MtrDisclosureVerification{
+			Collection:     disclosure.SourceCollection,
+			Key:            disclosure.TrackingId,
+			OnChainHash:    releasedHash,
+			PresentedHash:  hex.EncodeToString(presentedHash[:]),
+			SourceRevision: disclosure.SourceRevision,
+			CurrentHash:    hex.EncodeToString(currentHash),
+		}
+		verification.Matches = verification.OnChainHash == verification.PresentedHash
+		unchangedHash := releasedHash
+		if disclosure.LinkedSourceHash != "" {
+			unchangedHash = disclosure.LinkedSourceHash
+		}
+		verification.OriginalChanged = verification.CurrentHash != unchangedHash
+		verificationBytes, _ := json.Marshal(verification)
+		return shim.Success(verificationBytes)
+	}
+	return shim.Error("No released certificate found - " + args[0])
+}
+
+/*
+	Method: releasedCertificate
+	Reads the copy of a certificate released to the customer
+*/
+func releasedCertificate(stub shim.ChaincodeStubInterface, trackingId string) (MaterialCertificate, bool) {
+	materialCert := MaterialCertificate{}
+	for _, disclosureCollection := range mtrDisclosureCollections(currentMspId) {
+		disclosureBytes, err := stub.GetPrivateData(disclosureCollection, trackingId)
+		if err == nil && disclosureBytes != nil {
+			disclosure := MtrDisclosure{}
+			json.Unmarshal(disclosureBytes, &disclosure)
+			json.Unmarshal(disclosure.Certificate, &materialCert)
+			return materialCert, true
+		}
+	}
+	return materialCert, false
+}
+
+/*
+	Method: refreshMtrDisclosure
+	Records the on-chain hash of a released original rewritten by a link, which leaves its content as released
+*/
+func refreshMtrDisclosure(stub shim.ChaincodeStubInterface, sourceCollection string, trackingId string, certificateBytes []byte) error {
+	disclosureCollection := mtrDisclosureCollection(sourceCollection)
+	disclosureBytes, err := stub.GetPrivateData(disclosureCollection, trackingId)
+	if err != nil || disclosureBytes == nil {
+		return err
+	}
+	disclosure := MtrDisclosure{}
+	json.Unmarshal(disclosureBytes, &disclosure)
+	linkedHash := sha256.Sum256(certificateBytes)
+	disclosure.LinkedSourceHash = hex.EncodeToString(linkedHash[:])
+	disclosureBytes, _ = json.Marshal(disclosure)
+	return stub.PutPrivateData(disclosureCollection, trackingId, disclosureBytes)
+}
+
+/*
+	Method: mtrDisclosureCollection
+	Returns the customer readable collection for certificates kept in an MTR collection
+*/
+func mtrDisclosureCollection(sourceCollection string) string {
+	if sourceCollection == PRIVATE_COLLECTION_MTR_MFR2 {
+		return PRIVATE_COLLECTION_CUSTOMER_MTR_MFR2
+	}
+	return PRIVATE_COLLECTION_CUSTOMER_MTR_MFR1
+}
+
+func mtrDisclosureCollections(mspId string) []string {
+	switch mspId {
+	case "org3msp": // "manufacturer 1"
+		return []string{PRIVATE_COLLECTION_CUSTOMER_MTR_MFR1}
+	case "org4msp": // "manufacturer 2"
+		return []string{PRIVATE_COLLECTION_CUSTOMER_MTR_MFR2}
+	}
+	return []string{PRIVATE_COLLECTION_CUSTOMER_MTR_MFR1, PRIVATE_COLLECTION_CUSTOMER_MTR_MFR2}
+}
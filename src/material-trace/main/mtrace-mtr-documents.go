@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	TRANSIENT_MTR_DOCUMENT = "document"
+)
+
+/*
+	Method: anchorMtrDocument
+	Hashes the original certificate passed in the transient map under documentKey and records the hash with the certificate.
+	The bytes themselves stay off chain, only the hash, media type and storage URI are kept.
+*/
+func anchorMtrDocument(stub shim.ChaincodeStubInterface, materialCert *MaterialCertificate, documentKey string) error {
+	transientMap, err := stub.GetTransient()
+	if err != nil {
+		return err
+	}
+	documentBytes, found := transientMap[documentKey]
+	if !found || len(documentBytes) == 0 {
+		if materialCert.Document.Sha256 != "" {
+			return fmt.Errorf("the original document must be passed in the transient map under %s", documentKey)
+		}
+		return nil
+	}
+	if materialCert.Document.MediaType == "" || materialCert.Document.Uri == "" {
+		return fmt.Errorf("mediaType and uri are required for the certificate document")
+	}
+	hash := sha256.Sum256(documentBytes)
+	computed := hex.EncodeToString(hash[:])
+	if materialCert.Document.Sha256 != "" && !str.EqualFold(materialCert.Document.Sha256, computed) {
+		return fmt.Errorf("document hash %s does not match the document provided", materialCert.Document.Sha256)
+	}
+	materialCert.Document.Sha256 = computed
+	materialCert.Document.Size = int64(len(documentBytes))
+	return nil
+}
+
+/*
+	Method: verifyMtrDocument
+	Confirms that a file, passed in the transient map, matches the document hash recorded for a certificate.
+	The customer checks against the copy released to it.
+*/
+func (s *SmartContract) verifyMtrDocument(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. trackingId")
+	}
+	var materialCert MaterialCertificate
+	var found bool
+	if currentMspId == "org1msp" {
+		// the customer only has access to released copies
+		materialCert, found = releasedCertificate(stub, args[0])
+	} else {
+		materialCert, _, found = getMaterialCertificate(stub, args[0])
+	}
+	if !found {
+		return shim.Error("Material certificate not found - " + args[0])
+	}
+	if materialCert.Document.Sha256 == "" {
+		return shim.Error("No document anchored for material certificate - " + args[0])
+	}
+	transientMap, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	documentBytes, found := transientMap[TRANSIENT_MTR_DOCUMENT]
+	if !found || len(documentBytes) == 0 {
+		return shim.Error("The document to verify must be passed in the transient map under " + TRANSIENT_MTR_DOCUMENT)
+	}
+	hash := sha256.Sum256(documentBytes)
+	verification := MtrDocumentVerification{
+		TrackingId:    materialCert.TrackingId,
+		Uri:           materialCert.Document.Uri,
+		OnChainHash:   materialCert.Document.Sha256,
+		PresentedHash: hex.EncodeToString(hash[:]),
+	}
+	verification.Matches = str.EqualFold(verification.OnChainHash, verification.PresentedHash)
+	verificationBytes, _ := json.Marshal(verification)
+	return shim.Success(verificationBytes)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	MTR_IMPORT_CSV      = "csv"
+	MTR_IMPORT_JSON     = "json"
+	MTR_IMPORT_MAX_ROWS = 500
+)
+
+/*
+	Method: importMaterialCertificates
+	Executed when a manufacturer uploads a batch of certificates, either CSV with one row per heat
+	or a JSON array of MtrImportRecord. Columns and result names are mapped onto the template fields of the material group.
+	Every row is validated and stored in this transaction; if any row fails nothing is stored and the
+	per-row results are returned in the error message.
+	Original documents can be passed in the transient map under document:<trackingId>.
+	Signatures cover the results after they are mapped onto the template fields.
+*/
+func (s *SmartContract) importMaterialCertificates(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) < 2 || len(args) > 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 2 or 3. 1. format (csv|json) 2. certificates 3. column mapping (optional)")
+	}
+	privateCollection := mtrCollection(currentMspId)
+	if privateCollection == "" {
+		return shim.Error("Unexpected Organization Id - " + currentMspId)
+	}
+	columnMap := make(map[string]string)
+	if len(args) == 3 && args[2] != "" {
+		mapping := make(map[string]string)
+		if err := json.Unmarshal([]byte(args[2]), &mapping); err != nil {
+			return shim.Error("Unable to parse column mapping provided - " + args[2])
+		}
+		for column, field := range mapping {
+			columnMap[mtrFieldKey(column)] = field
+		}
+	}
+	var records []MtrImportRecord
+	var err error
+	switch str.ToLower(args[0]) {
+	case MTR_IMPORT_CSV:
+		records, err = parseMtrCsv(args[1], columnMap)
+	case MTR_IMPORT_JSON:
+		err = json.Unmarshal([]byte(args[1]), &records)
+	default:
+		return shim.Error("Unexpected import format - " + args[0] + ". Expecting csv or json")
+	}
+	if err != nil {
+		return Error(http.StatusBadRequest, "Unable to parse certificates provided - "+err.Error())
+	}
+	if len(records) == 0 || len(records) > MTR_IMPORT_MAX_ROWS {
+		return Error(http.StatusBadRequest, fmt.Sprintf("A batch must hold between 1 and %d certificates, found %d", MTR_IMPORT_MAX_ROWS, len(records)))
+	}
+	results := make([]MtrImportResult, len(records))
+	seen := make(map[string]int)
+	revisedItemKeys := make(map[string][]string)
+	failed := false
+	for i, record := range records {
+		results[i] = MtrImportResult{Row: i + 1, TrackingId: record.TrackingId}
+		// private reads do not see writes of the same transaction, so a tracking id may appear once per batch
+		if row, found := seen[record.TrackingId]; found && record.TrackingId != "" {
+			results[i].Error = fmt.Sprintf("duplicate of row %d", row)
+			failed = true
+			continue
+		}
+		seen[record.TrackingId] = i + 1
+		materialCert := MaterialCertificate{
+			ObjectType:     privateCollection,
+			TrackingId:     record.TrackingId,
+			MaterialGroup:  record.MaterialGroup,
+			Spec:           record.Spec,
+			Data:           mapMtrDetails(stub, record, columnMap),
+			ReferencedBy:   make([]string, 0),
+			Document:       record.Document,
+			RevisionReason: record.RevisionReason,
+		}
+		if record.Signature != "" {
+			materialCert.Signature = &MtrSignature{Value: record.Signature}
+		}
+		materialCert, err := storeMaterialCertificate(stub, privateCollection, materialCert, TRANSIENT_MTR_DOCUMENT+":"+record.TrackingId, revisedItemKeys)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		results[i].Imported = true
+		results[i].Revision = materialCert.Revision
+	}
+	if failed {
+		// the whole batch is rejected
+		for i := range results {
+			results[i].Imported = false
+		}
+		resultBytes, _ := json.Marshal(results)
+		return Error(http.StatusBadRequest, string(resultBytes))
+	}
+	// lines linked to several revised certificates of the batch are flagged in one write per PO
+	commitMtrRevisionFlags(stub, revisedItemKeys)
+	resultBytes, _ := json.Marshal(results)
+	return shim.Success(resultBytes)
+}
+
+/*
+	Method: parseMtrCsv
+	Reads a CSV batch. The header row names the columns; trackingId, materialGroup, spec,
+	heatNumber, revisionReason and signature are certificate properties, every other column is a result.
+*/
+func parseMtrCsv(document string, columnMap map[string]string) ([]MtrImportRecord, error) {
+	reader := csv.NewReader(str.NewReader(document))
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("a header row and at least one certificate row are expected")
+	}
+	header := make([]string, len(rows[0]))
+	for i, column := range rows[0] {
+		header[i] = str.TrimSpace(column)
+		if field, found := columnMap[mtrFieldKey(column)]; found {
+			header[i] = field
+		}
+	}
+	records := make([]MtrImportRecord, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := MtrImportRecord{Results: make([]MtrDetails, 0)}
+		for i, value := range row {
+			value = str.TrimSpace(value)
+			switch mtrFieldKey(header[i]) {
+			case "trackingid":
+				record.TrackingId = value
+			case "materialgroup":
+				record.MaterialGroup = value
+			case "spec":
+				record.Spec = value
+			case "heatnumber", "heat":
+				record.HeatNumber = value
+			case "revisionreason":
+				record.RevisionReason = value
+			case "signature":
+				record.Signature = value
+			default:
+				if value != "" {
+					record.Results = append(record.Results, MtrDetails{Name: header[i], Value: value})
+				}
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+/*
+	Method: mapMtrDetails
+	Names results after the matching template field of the material group and fills in the template unit
+*/
+func mapMtrDetails(stub shim.ChaincodeStubInterface, record MtrImportRecord, columnMap map[string]string) []MtrDetails {
+	fields := make(map[string]MtrTemplateField)
+	if template, found := getMtrTemplate(stub, record.MaterialGroup); found {
+		for _, field := range template.Fields {
+			fields[mtrFieldKey(field.Name)] = field
+		}
+	}
+	details := make([]MtrDetails, 0, len(record.Results)+1)
+	if record.HeatNumber != "" {
+		details = append(details, MtrDetails{Name: "Heat Number", Value: record.HeatNumber})
+	}
+	for _, detail := range record.Results {
+		if mapped, found := columnMap[mtrFieldKey(detail.Name)]; found {
+			detail.Name = mapped
+		}
+		if field, found := fields[mtrFieldKey(detail.Name)]; found {
+			detail.Name = field.Name
+			if detail.Unit == "" {
+				detail.Unit = field.Unit
+			}
+		}
+		if mtrFieldKey(detail.Name) == "heatnumber" && record.HeatNumber != "" {
+			continue
+		}
+		details = append(details, detail)
+	}
+	return details
+}
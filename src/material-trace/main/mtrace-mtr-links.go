@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_MTR_LINK = "mtrLink"
+)
+
+/*
+	Method: linkMtr
+	Executed when a manufacturer, or the distributor for inventory, attaches stored certificates to its order request.
+	The certificate records the order request in referencedBy and a summary is shared in the general progress collection.
+*/
+func (s *SmartContract) linkMtr(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. poId 2. itemKey 3. trackingIds 4. timeStamp")
+	}
+	poId := args[0]
+	itemKey := args[1]
+	trackingIds := []string{}
+	err := json.Unmarshal([]byte(args[2]), &trackingIds)
+	if err != nil || len(trackingIds) == 0 {
+		return shim.Error("Unable to parse trackingIds provided - " + args[2])
+	}
+	linkTimeStamp, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[3] + " Expecting a number.")
+	}
+	orderedLine, fulfilledBy, err := getOrderRequestLine(stub, poId, itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	links := make([]MtrLink, 0)
+	sharedMtrs := make([]Mtr, 0)
+	for _, trackingId := range trackingIds {
+		materialCert, privateCollection, found := getMaterialCertificate(stub, trackingId)
+		if !found {
+			return shim.Error("Material certificate not found - " + trackingId)
+		}
+		if orderedLine.MaterialGroup != "" && !str.EqualFold(materialCert.MaterialGroup, orderedLine.MaterialGroup) {
+			return shim.Error(fmt.Sprintf("Material certificate %s is for %s, line %d is %s", trackingId, materialCert.MaterialGroup, orderedLine.LineNumber, orderedLine.MaterialGroup))
+		}
+		reference := orderRequestNodeId(itemKey, fulfilledBy)
+		if !containsFold(materialCert.ReferencedBy, reference) {
+			materialCert.ReferencedBy = append(materialCert.ReferencedBy, reference)
+			mtrBytes, _ := json.Marshal(materialCert)
+			err = stub.PutPrivateData(privateCollection, materialCert.TrackingId, mtrBytes)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			if err := refreshMtrDisclosure(stub, privateCollection, materialCert.TrackingId, mtrBytes); err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+		link := MtrLink{
+			ObjectType:      DOC_TYPE_MTR_LINK,
+			TrackingId:      materialCert.TrackingId,
+			PoId:            poId,
+			PoNumber:        orderedLine.PoNumber,
+			LineNumber:      orderedLine.LineNumber,
+			ItemKey:         itemKey,
+			FulfilledBy:     fulfilledBy,
+			MaterialGroup:   materialCert.MaterialGroup,
+			Spec:            materialCert.Spec,
+			HeatNumber:      heatNumberFromMtr(materialCert),
+			Compliance:      materialCert.Compliance.Verdict,
+			DocumentSha256:  materialCert.Document.Sha256,
+			LinkedTimeStamp: linkTimeStamp,
+			Revision:        materialCert.Revision,
+		}
+		if err := putMtrLink(stub, link); err != nil {
+			return shim.Error(err.Error())
+		}
+		recordMtrGenealogy(stub, link.HeatNumber, link.TrackingId, poId, itemKey, fulfilledBy, linkTimeStamp)
+		links = append(links, link)
+		sharedMtrs = append(sharedMtrs, Mtr{Name: "trackingId", Value: link.TrackingId})
+	}
+	// Add progress to shared table
+	sharedItemsMap := map[int]LineItem{orderedLine.LineNumber: {LineNumber: orderedLine.LineNumber, MaterialCertificate: sharedMtrs}}
+	updateSharedProgressRecord(stub, poId, sharedItemsMap, ItemStatus{Owner: fulfilledBy, Status: STATUS_MTR_LINKED, TimeStamp: linkTimeStamp}, MODE_MTR_LINKED)
+
+	linkBytes, _ := json.Marshal(links)
+	return shim.Success(linkBytes)
+}
+
+/*
+	Method: queryMtrLinks
+	Returns the certificate summaries linked to a purchase order, optionally for a single line item
+*/
+func (s *SmartContract) queryMtrLinks(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. poId 2. itemKey (optional)")
+	}
+	itemKey := ""
+	if len(args) > 1 {
+		itemKey = args[1]
+	}
+	links, err := getMtrLinks(stub, args[0], itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	linkBytes, _ := json.Marshal(links)
+	return shim.Success(linkBytes)
+}
+
+/*
+	Method: checkLineCertificates
+	Gathers the certificates of a line being shipped, from links and from the client input, and checks them.
+	Lines of a material group whose template requires an MTR cannot ship without one.
+	Returns the certificate list to share with the customer and the compliance verdict.
+*/
+func checkLineCertificates(stub shim.ChaincodeStubInterface, poId string, itemKey string, fulfilledBy string, lineNumber int, materialId string, materialGroup string, mtrs []Mtr) ([]Mtr, string, error) {
+	trackingIds := linkedTrackingIds(mtrs)
+	links, _ := getMtrLinks(stub, poId, itemKey)
+	for _, link := range links {
+		if link.FulfilledBy != fulfilledBy || containsFold(trackingIds, link.TrackingId) {
+			continue
+		}
+		trackingIds = append(trackingIds, link.TrackingId)
+		mtrs = append(mtrs, Mtr{Name: "trackingId", Value: link.TrackingId})
+	}
+	template, templateFound := getMtrTemplate(stub, materialGroup)
+	if templateFound {
+		if template.RequireMtr && len(trackingIds) == 0 {
+			return mtrs, "", fmt.Errorf("line %d cannot ship: material group %s requires a material certificate, link one with link-mtr first", lineNumber, materialGroup)
+		}
+		for _, trackingId := range trackingIds {
+			if err := checkAttestations(stub, INSPECTION_TARGET_MTR, trackingId, template.RequiredAttestations); err != nil {
+				return mtrs, "", fmt.Errorf("line %d cannot ship: %s", lineNumber, err.Error())
+			}
+		}
+	}
+	compliance, err := checkLineCompliance(stub, lineNumber, materialId, materialGroup, trackingIds)
+	if err != nil {
+		return mtrs, compliance, err
+	}
+	// a group that requires a passing verdict does not ship lines still pending one
+	if templateFound && template.RequirePassingVerdict && compliance == COMPLIANCE_VERDICT_PENDING {
+		return mtrs, compliance, fmt.Errorf("line %d cannot ship without a passing compliance verdict: verdict is pending", lineNumber)
+	}
+	return mtrs, compliance, nil
+}
+
+/*
+	Method: recordMtrGenealogy
+	Links a heat to its certificate and the certificate to an order request in the shared genealogy
+*/
+func recordMtrGenealogy(stub shim.ChaincodeStubInterface, heatNumber string, trackingId string, poId string, itemKey string, fulfilledBy string, timeStamp int64) {
+	if heatNumber != "" {
+		recordGenealogyEdge(stub, PRIVATE_COLLECTION_GENERAL_PROGRESS, GenealogyEdge{FromType: GENEALOGY_NODE_HEAT, FromId: heatNumber, ToType: GENEALOGY_NODE_MTR, ToId: trackingId, PoId: poId, TimeStamp: timeStamp})
+	}
+	recordGenealogyEdge(stub, PRIVATE_COLLECTION_GENERAL_PROGRESS, GenealogyEdge{FromType: GENEALOGY_NODE_MTR, FromId: trackingId, ToType: GENEALOGY_NODE_ORDER_REQUEST, ToId: orderRequestNodeId(itemKey, fulfilledBy), PoId: poId, TimeStamp: timeStamp})
+}
+
+func putMtrLink(stub shim.ChaincodeStubInterface, link MtrLink) error {
+	linkKey, err := stub.CreateCompositeKey(DOC_TYPE_MTR_LINK, []string{link.PoId, link.ItemKey, link.TrackingId})
+	if err != nil {
+		return err
+	}
+	linkBytes, _ := json.Marshal(link)
+	return stub.PutPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, linkKey, linkBytes)
+}
+
+func getMtrLinks(stub shim.ChaincodeStubInterface, poId string, itemKey string) ([]MtrLink, error) {
+	keys := []string{poId}
+	if itemKey != "" {
+		keys = append(keys, itemKey)
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_GENERAL_PROGRESS, DOC_TYPE_MTR_LINK, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	links := make([]MtrLink, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		link := MtrLink{}
+		json.Unmarshal(queryResponse.Value, &link)
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	MTR_QUERY_DEFAULT_PAGE_SIZE = 50
+	MTR_QUERY_MAX_PAGE_SIZE     = 200
+)
+
+/*
+	Method: queryMtrs
+	Returns a page of current certificate revisions filtered by heat number, grade, material group,
+	upload date range and linked PO. The distributor pages through both manufacturer collections.
+	The shim only paginates queries on public state, so pages follow the certificate keys and the
+	bookmark is <collection>:<last tracking id returned>.
+*/
+func (s *SmartContract) queryMtrs(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. mtr query")
+	}
+	query := MtrQuery{}
+	err := json.Unmarshal([]byte(args[0]), &query)
+	if err != nil {
+		return shim.Error("Unable to parse mtr query provided - " + args[0])
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = MTR_QUERY_DEFAULT_PAGE_SIZE
+	}
+	if query.PageSize > MTR_QUERY_MAX_PAGE_SIZE {
+		query.PageSize = MTR_QUERY_MAX_PAGE_SIZE
+	}
+	collections := mtrCollections(currentMspId)
+	startIndex, startAfter, err := parseMtrBookmark(query.Bookmark, collections)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	var linkedTrackingIds []string
+	if query.PoId != "" {
+		linkedTrackingIds, err = poTrackingIds(stub, query.PoId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	page := MtrQueryPage{Records: make([]MaterialCertificate, 0)}
+	for i := startIndex; i < len(collections); i++ {
+		selector := mtrSelector(collections[i], query)
+		if query.PoId != "" {
+			selector["trackingId"] = map[string]interface{}{"$in": linkedTrackingIds}
+		}
+		after := ""
+		if i == startIndex {
+			after = startAfter
+		}
+		remaining := query.PageSize - len(page.Records)
+		// one extra record tells whether the collection has more
+		records, err := getMtrs(stub, collections[i], selector, after, remaining+1)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if len(records) > remaining {
+			page.Records = append(page.Records, records[:remaining]...)
+			page.Bookmark = collections[i] + ":" + records[remaining-1].TrackingId
+			break
+		}
+		page.Records = append(page.Records, records...)
+		if len(page.Records) == query.PageSize && i+1 < len(collections) {
+			page.Bookmark = collections[i+1] + ":"
+			break
+		}
+	}
+	page.Count = len(page.Records)
+	pageBytes, _ := json.Marshal(page)
+	return shim.Success(pageBytes)
+}
+
+/*
+	Method: getMtrs
+	Runs an MTR selector on a collection and returns up to limit records in key order,
+	starting after the key given or from the first key when it is empty
+*/
+func getMtrs(stub shim.ChaincodeStubInterface, privateCollection string, selector map[string]interface{}, after string, limit int) ([]MaterialCertificate, error) {
+	if after != "" {
+		selector["_id"] = map[string]interface{}{"$gt": after}
+	}
+	queryBytes, _ := json.Marshal(map[string]interface{}{
+		"selector": selector,
+		"sort":     []map[string]string{{"_id": "asc"}},
+		"limit":    limit,
+	})
+	resultsIterator, err := stub.GetPrivateDataQueryResult(privateCollection, string(queryBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	records := make([]MaterialCertificate, 0)
+	for resultsIterator.HasNext() && len(records) < limit {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		materialCert := MaterialCertificate{}
+		json.Unmarshal(queryResponse.Value, &materialCert)
+		records = append(records, materialCert)
+	}
+	return records, nil
+}
+
+/*
+	Method: poTrackingIds
+	Tracking ids of the certificates linked to any line of a PO
+*/
+func poTrackingIds(stub shim.ChaincodeStubInterface, poId string) ([]string, error) {
+	links, err := getMtrLinks(stub, poId, "")
+	if err != nil {
+		return nil, err
+	}
+	trackingIds := make(map[string]bool)
+	for _, link := range links {
+		trackingIds[link.TrackingId] = true
+	}
+	return sortedKeys(trackingIds), nil
+}
+
+/*
+	Method: mtrSelector
+	Builds the Mango selector of an MTR query. Archived revisions are excluded, see reviseMaterialCertificate.
+	The heatNumber, grade, materialGroup and uploadedTimeStamp filters are served by the indexes under META-INF.
+	The PO filter needs the certificate links, see queryMtrs.
+*/
+func mtrSelector(privateCollection string, query MtrQuery) map[string]interface{} {
+	selector := map[string]interface{}{
+		"docType":      privateCollection,
+		"supersededBy": map[string]interface{}{"$exists": false},
+	}
+	if query.HeatNumber != "" {
+		selector["heatNumber"] = query.HeatNumber
+	}
+	if query.Grade != "" {
+		selector["grade"] = query.Grade
+	}
+	if query.MaterialGroup != "" {
+		selector["materialGroup"] = query.MaterialGroup
+	}
+	uploaded := make(map[string]interface{})
+	if query.UploadedFrom > 0 {
+		uploaded["$gte"] = query.UploadedFrom
+	}
+	if query.UploadedTo > 0 {
+		uploaded["$lte"] = query.UploadedTo
+	}
+	if len(uploaded) > 0 {
+		selector["uploadedTimeStamp"] = uploaded
+	}
+	return selector
+}
+
+func parseMtrBookmark(bookmark string, collections []string) (int, string, error) {
+	if bookmark == "" {
+		return 0, "", nil
+	}
+	separator := str.Index(bookmark, ":")
+	if separator > 0 {
+		for i, collection := range collections {
+			if collection == bookmark[:separator] {
+				return i, bookmark[separator+1:], nil
+			}
+		}
+	}
+	return 0, "", fmt.Errorf("unexpected bookmark - %s", bookmark)
+}
+
+/*
+	Method: mtrCollections
+	MTR collections an organization can query, see collections_config.json
+*/
+func mtrCollections(mspId string) []string {
+	if mspId == "org2msp" {
+		return []string{PRIVATE_COLLECTION_MTR_MFR1, PRIVATE_COLLECTION_MTR_MFR2}
+	}
+	if privateCollection := mtrCollection(mspId); privateCollection != "" {
+		return []string{privateCollection}
+	}
+	return []string{}
+}
+
+/*
+	Method: gradeFromMtr
+	Returns the grade recorded on a material certificate
+*/
+func gradeFromMtr(materialCert MaterialCertificate) string {
+	for _, detail := range materialCert.Data {
+		if mtrFieldKey(detail.Name) == "grade" {
+			return detail.Value
+		}
+	}
+	return ""
+}
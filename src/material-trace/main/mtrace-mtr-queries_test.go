@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestParseMtrBookmark(t *testing.T) {
+	collections := []string{PRIVATE_COLLECTION_MTR_MFR1, PRIVATE_COLLECTION_MTR_MFR2}
+	tests := []struct {
+		name      string
+		bookmark  string
+		wantIndex int
+		wantAfter string
+		wantErr   bool
+	}{
+		{"first page", "", 0, "", false},
+		{"within a collection", PRIVATE_COLLECTION_MTR_MFR1 + ":MTR-0042", 0, "MTR-0042", false},
+		{"start of the next collection", PRIVATE_COLLECTION_MTR_MFR2 + ":", 1, "", false},
+		{"tracking id with a colon", PRIVATE_COLLECTION_MTR_MFR2 + ":H1:MTR-7", 1, "H1:MTR-7", false},
+		{"collection not queried", PRIVATE_COLLECTION_LOGISTICS + ":MTR-1", 0, "", true},
+		{"no collection", "MTR-1", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, after, err := parseMtrBookmark(tt.bookmark, collections)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMtrBookmark() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if index != tt.wantIndex || after != tt.wantAfter {
+				t.Errorf("parseMtrBookmark() = %d, %q, want %d, %q", index, after, tt.wantIndex, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_MTR_REVISION = "mtrRevision"
+)
+
+/*
+	Method: reviseMaterialCertificate
+	Called before a certificate is stored. When one already exists under the tracking id a revision reason is required,
+	the stored certificate is archived under its revision number and the new one supersedes it, keeping its links.
+	Returns the superseded certificate and whether this is a revision.
+*/
+func reviseMaterialCertificate(stub shim.ChaincodeStubInterface, privateCollection string, materialCert *MaterialCertificate) (MaterialCertificate, bool, error) {
+	previousCert := MaterialCertificate{}
+	previousBytes, err := stub.GetPrivateData(privateCollection, materialCert.TrackingId)
+	if err != nil {
+		return previousCert, false, err
+	}
+	if previousBytes == nil {
+		materialCert.Revision = 1
+		materialCert.Supersedes = 0
+		materialCert.SupersededBy = 0
+		return previousCert, false, nil
+	}
+	if str.TrimSpace(materialCert.RevisionReason) == "" {
+		return previousCert, false, fmt.Errorf("material certificate %s already exists, a revisionReason is required to revise it", materialCert.TrackingId)
+	}
+	json.Unmarshal(previousBytes, &previousCert)
+	if previousCert.Revision == 0 {
+		// stored before revisions were kept
+		previousCert.Revision = 1
+	}
+	materialCert.Revision = previousCert.Revision + 1
+	materialCert.Supersedes = previousCert.Revision
+	materialCert.SupersededBy = 0
+	for _, reference := range previousCert.ReferencedBy {
+		if !containsFold(materialCert.ReferencedBy, reference) {
+			materialCert.ReferencedBy = append(materialCert.ReferencedBy, reference)
+		}
+	}
+	previousCert.SupersededBy = materialCert.Revision
+	revisionKey, err := mtrRevisionKey(stub, previousCert.TrackingId, previousCert.Revision)
+	if err != nil {
+		return previousCert, false, err
+	}
+	revisionBytes, _ := json.Marshal(previousCert)
+	err = stub.PutPrivateData(privateCollection, revisionKey, revisionBytes)
+	if err != nil {
+		return previousCert, false, err
+	}
+	return previousCert, true, nil
+}
+
+/*
+	Method: flagMtrRevision
+	Flags the order requests linked to a revised certificate for review and adds their item keys, by PO,
+	to revisedItemKeys. The PO lines are flagged by commitMtrRevisionFlags once every certificate
+	of the transaction is stored, as private reads do not see the writes of the same transaction.
+*/
+func flagMtrRevision(stub shim.ChaincodeStubInterface, materialCert MaterialCertificate, revisedItemKeys map[string][]string) {
+	for _, edge := range getGenealogyEdges(stub, []string{PRIVATE_COLLECTION_GENERAL_PROGRESS}, GENEALOGY_NODE_MTR, materialCert.TrackingId, GENEALOGY_FORWARD) {
+		if edge.ToType != GENEALOGY_NODE_ORDER_REQUEST || edge.PoId == "" {
+			continue
+		}
+		// order request node ids are itemKey|fulfilledBy
+		itemKey := edge.ToId
+		if i := str.LastIndex(itemKey, "|"); i > 0 {
+			itemKey = itemKey[:i]
+		}
+		links, _ := getMtrLinks(stub, edge.PoId, itemKey)
+		for _, link := range links {
+			if link.TrackingId != materialCert.TrackingId {
+				continue
+			}
+			link.Revision = materialCert.Revision
+			link.ReviewRequired = true
+			putMtrLink(stub, link)
+		}
+		if !containsFold(revisedItemKeys[edge.PoId], itemKey) {
+			revisedItemKeys[edge.PoId] = append(revisedItemKeys[edge.PoId], itemKey)
+		}
+	}
+}
+
+/*
+	Method: commitMtrRevisionFlags
+	Flags the PO lines linked to the certificates revised in a transaction, writing each PO's progress once
+*/
+func commitMtrRevisionFlags(stub shim.ChaincodeStubInterface, revisedItemKeys map[string][]string) {
+	itemStatus := ItemStatus{Owner: organizationMap[currentMspId], Status: STATUS_MTR_REVISED, TimeStamp: txTimeStamp(stub)}
+	poIds := make(map[string]bool)
+	for poId := range revisedItemKeys {
+		poIds[poId] = true
+	}
+	for _, poId := range sortedKeys(poIds) {
+		sharedItemsMap := sharedLinesByItemKey(stub, poId, revisedItemKeys[poId])
+		updateSharedProgressRecord(stub, poId, sharedItemsMap, itemStatus, MODE_MTR_REVISED)
+	}
+}
+
+/*
+	Method: reviewMtrRevision
+	Executed when the customer or distributor has reviewed a revised certificate on a line item and clears the flag
+*/
+func (s *SmartContract) reviewMtrRevision(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. poId 2. itemKey 3. trackingId 4. timeStamp")
+	}
+	poId := args[0]
+	itemKey := args[1]
+	reviewTimeStamp, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[3] + " Expecting a number.")
+	}
+	links, err := getMtrLinks(stub, poId, itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	reviewed := false
+	pending := false
+	for _, link := range links {
+		if link.TrackingId == args[2] && link.ReviewRequired {
+			link.ReviewRequired = false
+			if err := putMtrLink(stub, link); err != nil {
+				return shim.Error(err.Error())
+			}
+			reviewed = true
+			continue
+		}
+		pending = pending || link.ReviewRequired
+	}
+	if !reviewed {
+		return shim.Error("No revised certificate " + args[2] + " awaiting review on " + itemKey)
+	}
+	if !pending {
+		sharedItemsMap := sharedLinesByItemKey(stub, poId, []string{itemKey})
+		updateSharedProgressRecord(stub, poId, sharedItemsMap, ItemStatus{Owner: organizationMap[currentMspId], Status: STATUS_MTR_REVISION_REVIEWED, TimeStamp: reviewTimeStamp}, MODE_MTR_REVIEWED)
+	}
+	links, _ = getMtrLinks(stub, poId, itemKey)
+	linkBytes, _ := json.Marshal(links)
+	return shim.Success(linkBytes)
+}
+
+/*
+	Method: queryMtrRevisions
+	Returns every revision of a certificate, oldest first, ending with the current one
+*/
+func (s *SmartContract) queryMtrRevisions(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. trackingId")
+	}
+	currentCert, privateCollection, found := getMaterialCertificate(stub, args[0])
+	if !found {
+		return shim.Error("Material certificate not found - " + args[0])
+	}
+	revisions := make([]MaterialCertificate, 0)
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection, DOC_TYPE_MTR_REVISION, []string{currentCert.TrackingId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		revision := MaterialCertificate{}
+		json.Unmarshal(queryResponse.Value, &revision)
+		revisions = append(revisions, revision)
+	}
+	revisions = append(revisions, currentCert)
+	sort.SliceStable(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	revisionBytes, _ := json.Marshal(revisions)
+	return shim.Success(revisionBytes)
+}
+
+func mtrRevisionKey(stub shim.ChaincodeStubInterface, trackingId string, revision int) (string, error) {
+	return stub.CreateCompositeKey(DOC_TYPE_MTR_REVISION, []string{trackingId, fmt.Sprintf("%06d", revision)})
+}
+
+/*
+	Method: sharedLinesByItemKey
+	Returns the shared progress lines of a purchase order for the given item keys, keyed by line number
+*/
+func sharedLinesByItemKey(stub shim.ChaincodeStubInterface, poId string, itemKeys []string) map[int]LineItem {
+	sharedItemsMap := make(map[int]LineItem)
+	privateDataResponse, _ := stub.GetPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, poId)
+	if privateDataResponse == nil {
+		return sharedItemsMap
+	}
+	sharedProgress := SharedProgressReport{}
+	json.Unmarshal(privateDataResponse, &sharedProgress)
+	for _, line := range sharedProgress.LineItems {
+		if containsFold(itemKeys, line.ItemKey) {
+			sharedItemsMap[line.LineNumber] = LineItem{LineNumber: line.LineNumber, ItemKey: line.ItemKey}
+		}
+	}
+	return sharedItemsMap
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	str "strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	MTR_SIGNER_ROLE_ATTRIBUTE = "role"
+	MTR_SIGNER_ROLE           = "quality-officer"
+)
+
+// Fabric CA stores enrollment attributes in this certificate extension
+var fabricAttributesOid = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+/*
+	Method: signMaterialCertificate
+	Verifies the signature a manufacturer supplied with a certificate against the X.509 certificate of the
+	submitting identity, which must be enrolled as a quality officer, and records the signer with it.
+	Certificates of a material group whose template requires a signature cannot be stored unsigned.
+*/
+func signMaterialCertificate(stub shim.ChaincodeStubInterface, materialCert *MaterialCertificate) error {
+	if materialCert.Signature == nil || materialCert.Signature.Value == "" {
+		materialCert.Signature = nil
+		if template, found := getMtrTemplate(stub, materialCert.MaterialGroup); found && template.RequireSignature {
+			return fmt.Errorf("material group %s requires certificates signed by a %s", materialCert.MaterialGroup, MTR_SIGNER_ROLE)
+		}
+		return nil
+	}
+	mspId, cert, certPem, err := creatorCertificate(stub)
+	if err != nil {
+		return err
+	}
+	role, err := certificateAttribute(cert, MTR_SIGNER_ROLE_ATTRIBUTE)
+	if err != nil {
+		return err
+	}
+	if !str.EqualFold(role, MTR_SIGNER_ROLE) {
+		return fmt.Errorf("certificates must be signed by a %s, submitting identity %s is not", MTR_SIGNER_ROLE, cert.Subject.CommonName)
+	}
+	signature := MtrSignature{
+		Value:             materialCert.Signature.Value,
+		SignerMspId:       mspId,
+		SignerSubject:     cert.Subject.String(),
+		SignerIssuer:      cert.Issuer.String(),
+		SignerCertificate: string(certPem),
+		SignedTimeStamp:   txTimeStamp(stub),
+	}
+	digest := mtrSignedDigest(*materialCert)
+	signature.ContentSha256 = hex.EncodeToString(digest)
+	signature.Algorithm, err = verifySignature(cert, digest, signature.Value)
+	if err != nil {
+		return fmt.Errorf("signature of material certificate %s does not verify: %s", materialCert.TrackingId, err.Error())
+	}
+	materialCert.Signature = &signature
+	return nil
+}
+
+/*
+	Method: verifyMtrSignature
+	Confirms a stored certificate still matches the content its manufacturer signed,
+	using the signer certificate recorded at signing time
+*/
+func (s *SmartContract) verifyMtrSignature(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. trackingId")
+	}
+	materialCert, found := inspectionCertificate(stub, args[0])
+	if !found {
+		return shim.Error("Material certificate not found - " + args[0])
+	}
+	if materialCert.Signature == nil {
+		return shim.Error("Material certificate is not signed - " + args[0])
+	}
+	digest := mtrSignedDigest(materialCert)
+	verification := MtrSignatureVerification{
+		TrackingId:      materialCert.TrackingId,
+		Revision:        materialCert.Revision,
+		SignerMspId:     materialCert.Signature.SignerMspId,
+		SignerSubject:   materialCert.Signature.SignerSubject,
+		SignedTimeStamp: materialCert.Signature.SignedTimeStamp,
+		ContentSha256:   hex.EncodeToString(digest),
+		ContentMatches:  materialCert.Signature.ContentSha256 == hex.EncodeToString(digest),
+	}
+	block, _ := pem.Decode([]byte(materialCert.Signature.SignerCertificate))
+	if block == nil {
+		return shim.Error("Unable to decode the signer certificate of " + args[0])
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	signedAt := materialCert.Signature.SignedTimeStamp / 1000
+	verification.CertificateValidWhenSigned = cert.NotBefore.Unix() <= signedAt && signedAt <= cert.NotAfter.Unix()
+	_, err = verifySignature(cert, digest, materialCert.Signature.Value)
+	verification.SignatureValid = err == nil
+	if err != nil {
+		verification.Error = err.Error()
+	}
+	verification.Authentic = verification.ContentMatches && verification.SignatureValid && verification.CertificateValidWhenSigned
+	verificationBytes, _ := json.Marshal(verification)
+	return shim.Success(verificationBytes)
+}
+
+/*
+	Method: mtrSignedDigest
+	SHA-256 of the canonical signed content of a certificate, which clients sign as well:
+	a JSON object with trackingId, materialGroup, spec, data and documentSha256 in this order,
+	data as a list of {"name","value","unit"} objects with unit left out when empty ([] without data)
+	and documentSha256 in lower case hex. It is UTF-8 encoded with no whitespace between tokens
+	and <, > and & are not escaped, e.g.
+	{"trackingId":"T1","materialGroup":"pipe","spec":"API 5L","data":[{"name":"C","value":"<= 0.26"}],"documentSha256":""}
+*/
+func mtrSignedDigest(materialCert MaterialCertificate) []byte {
+	content := MtrSignedContent{
+		TrackingId:     materialCert.TrackingId,
+		MaterialGroup:  materialCert.MaterialGroup,
+		Spec:           materialCert.Spec,
+		Data:           materialCert.Data,
+		DocumentSha256: str.ToLower(materialCert.Document.Sha256),
+	}
+	if content.Data == nil {
+		content.Data = make([]MtrDetails, 0)
+	}
+	var contentBytes bytes.Buffer
+	encoder := json.NewEncoder(&contentBytes)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(content)
+	digest := sha256.Sum256(bytes.TrimSuffix(contentBytes.Bytes(), []byte("\n")))
+	return digest[:]
+}
+
+/*
+	Method: verifySignature
+	Checks a base64 encoded ECDSA (ASN.1) or RSA PKCS#1 v1.5 signature over a SHA-256 digest
+*/
+func verifySignature(cert *x509.Certificate, digest []byte, encodedSignature string) (string, error) {
+	signatureBytes, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", fmt.Errorf("signature is not base64 encoded")
+	}
+	switch publicKey := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		ecdsaSignature := struct{ R, S *big.Int }{}
+		if _, err := asn1.Unmarshal(signatureBytes, &ecdsaSignature); err != nil {
+			return "", fmt.Errorf("malformed ECDSA signature")
+		}
+		if !ecdsa.Verify(publicKey, digest, ecdsaSignature.R, ecdsaSignature.S) {
+			return "", fmt.Errorf("ECDSA signature mismatch")
+		}
+		return "ECDSA-SHA256", nil
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signatureBytes); err != nil {
+			return "", fmt.Errorf("RSA signature mismatch")
+		}
+		return "RSA-SHA256", nil
+	}
+	return "", fmt.Errorf("unsupported public key type")
+}
+
+/*
+	Method: creatorCertificate
+	Returns the MSP and X.509 certificate of the client that signed the transaction
+*/
+func creatorCertificate(stub shim.ChaincodeStubInterface) (string, *x509.Certificate, []byte, error) {
+	creatorByte, err := stub.GetCreator()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	si := &msp.SerializedIdentity{}
+	err = proto.Unmarshal(creatorByte, si)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	block, _ := pem.Decode(si.IdBytes)
+	if block == nil {
+		return si.Mspid, nil, nil, fmt.Errorf("unable to decode the certificate of the submitting identity")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return si.Mspid, nil, nil, err
+	}
+	return si.Mspid, cert, si.IdBytes, nil
+}
+
+/*
+	Method: creatorIdentity
+	Returns the MSP, subject and certificate hash of the client that signed the transaction
+*/
+func creatorIdentity(stub shim.ChaincodeStubInterface) (SignerIdentity, error) {
+	mspId, cert, _, err := creatorCertificate(stub)
+	if err != nil {
+		return SignerIdentity{MspId: mspId}, err
+	}
+	certHash := sha256.Sum256(cert.Raw)
+	return SignerIdentity{
+		MspId:             mspId,
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		CertificateSha256: hex.EncodeToString(certHash[:]),
+	}, nil
+}
+
+/*
+	Method: certificateAttribute
+	Returns an enrollment attribute issued by Fabric CA, empty when the certificate has none
+*/
+func certificateAttribute(cert *x509.Certificate, name string) (string, error) {
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(fabricAttributesOid) {
+			continue
+		}
+		attributes := struct {
+			Attrs map[string]string `json:"attrs"`
+		}{}
+		if err := json.Unmarshal(extension.Value, &attributes); err != nil {
+			return "", fmt.Errorf("unable to read certificate attributes")
+		}
+		return attributes.Attrs[name], nil
+	}
+	return "", nil
+}
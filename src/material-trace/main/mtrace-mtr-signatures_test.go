@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMtrSignedDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    MaterialCertificate
+		content string
+	}{
+		{
+			"no data",
+			MaterialCertificate{TrackingId: "T1", MaterialGroup: "pipe"},
+			`{"trackingId":"T1","materialGroup":"pipe","spec":"","data":[],"documentSha256":""}`,
+		},
+		{
+			"comparison operators are not escaped",
+			MaterialCertificate{TrackingId: "T1", MaterialGroup: "pipe", Spec: "API 5L X52", Data: []MtrDetails{{Name: "C", Value: "<= 0.26"}, {Name: "Notes", Value: "Mn & Si > min"}}},
+			`{"trackingId":"T1","materialGroup":"pipe","spec":"API 5L X52","data":[{"name":"C","value":"<= 0.26"},{"name":"Notes","value":"Mn & Si > min"}],"documentSha256":""}`,
+		},
+		{
+			"unit only when set",
+			MaterialCertificate{TrackingId: "T2", MaterialGroup: "pipe", Data: []MtrDetails{{Name: "Yield", Value: "52000", Unit: "psi"}, {Name: "Heat", Value: "H1"}}},
+			`{"trackingId":"T2","materialGroup":"pipe","spec":"","data":[{"name":"Yield","value":"52000","unit":"psi"},{"name":"Heat","value":"H1"}],"documentSha256":""}`,
+		},
+		{
+			"document hash in lower case",
+			MaterialCertificate{TrackingId: "T3", MaterialGroup: "valve", Document: MtrDocument{Sha256: "ABCDEF"}, ReferencedBy: []string{"PO1"}},
+			`{"trackingId":"T3","materialGroup":"valve","spec":"","data":[],"documentSha256":"abcdef"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := sha256.Sum256([]byte(tt.content))
+			got := mtrSignedDigest(tt.cert)
+			if hex.EncodeToString(got) != hex.EncodeToString(want[:]) {
+				t.Errorf("digest does not match the canonical content %s", tt.content)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	str "strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_MTR_TEMPLATE = "mtrTemplate"
+	MTR_FIELD_STRING      = "string"
+	MTR_FIELD_NUMBER      = "number"
+	MTR_FIELD_BOOLEAN     = "boolean"
+	MTR_FIELD_DATE        = "date"
+)
+
+/*
+	Method: setMtrTemplate
+	Adds or replaces the MTR template of a material group
+*/
+func (s *SmartContract) setMtrTemplate(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. mtr template")
+	}
+	template := MtrTemplate{}
+	err := json.Unmarshal([]byte(args[0]), &template)
+	if err != nil {
+		return shim.Error("Unable to parse mtr template data provided - " + args[0])
+	}
+	if template.MaterialGroup == "" || len(template.Fields) == 0 {
+		return shim.Error("materialGroup and at least one field are required.")
+	}
+	seen := make(map[string]bool)
+	for i, field := range template.Fields {
+		if field.Name == "" {
+			return shim.Error("Every template field needs a name.")
+		}
+		if seen[mtrFieldKey(field.Name)] {
+			return shim.Error("Duplicate template field - " + field.Name)
+		}
+		seen[mtrFieldKey(field.Name)] = true
+		template.Fields[i].Type = str.ToLower(field.Type)
+		switch template.Fields[i].Type {
+		case "":
+			template.Fields[i].Type = MTR_FIELD_STRING
+		case MTR_FIELD_STRING, MTR_FIELD_NUMBER, MTR_FIELD_BOOLEAN, MTR_FIELD_DATE:
+		default:
+			return shim.Error("Unexpected type for field " + field.Name + ". Found: " + field.Type)
+		}
+	}
+	for _, attestationTypes := range [][]string{template.RequiredAttestations, template.ShipmentAttestations} {
+		for i, attestationType := range attestationTypes {
+			attestationTypes[i] = str.ToLower(attestationType)
+			if !validAttestationType(attestationTypes[i]) {
+				return shim.Error("Unexpected attestation type - " + attestationType)
+			}
+		}
+	}
+	templateKey, err := stub.CreateCompositeKey(DOC_TYPE_MTR_TEMPLATE, []string{str.ToLower(template.MaterialGroup)})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	template.ObjectType = DOC_TYPE_MTR_TEMPLATE
+	template.UpdatedBy = organizationMap[currentMspId]
+	templateBytes, _ := json.Marshal(template)
+	err = stub.PutState(templateKey, templateBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(templateBytes)
+}
+
+/*
+	Method: queryMtrTemplates
+	Returns the MTR templates, optionally for a single material group
+*/
+func (s *SmartContract) queryMtrTemplates(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	keys := []string{}
+	if len(args) > 0 && args[0] != "" {
+		keys = []string{str.ToLower(args[0])}
+	}
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(DOC_TYPE_MTR_TEMPLATE, keys)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	templates := make([]MtrTemplate, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		template := MtrTemplate{}
+		json.Unmarshal(queryResponse.Value, &template)
+		templates = append(templates, template)
+	}
+	templateBytes, _ := json.Marshal(templates)
+	return shim.Success(templateBytes)
+}
+
+/*
+	Method: validateMaterialCertificate
+	Checks a certificate against the template of its material group.
+	Groups without a template accept any data.
+*/
+func validateMaterialCertificate(stub shim.ChaincodeStubInterface, materialCert MaterialCertificate) error {
+	if materialCert.TrackingId == "" || materialCert.MaterialGroup == "" {
+		return fmt.Errorf("trackingId and materialGroup are required")
+	}
+	template, found := getMtrTemplate(stub, materialCert.MaterialGroup)
+	if !found {
+		return nil
+	}
+	values := make(map[string]MtrDetails)
+	for _, detail := range materialCert.Data {
+		values[mtrFieldKey(detail.Name)] = detail
+	}
+	problems := make([]string, 0)
+	for _, field := range template.Fields {
+		detail, found := values[mtrFieldKey(field.Name)]
+		if !found || str.TrimSpace(detail.Value) == "" {
+			if field.Required {
+				problems = append(problems, field.Name+" is required")
+			}
+			continue
+		}
+		if field.Unit != "" && detail.Unit != "" && !str.EqualFold(field.Unit, detail.Unit) {
+			problems = append(problems, fmt.Sprintf("%s must be in %s, found %s", field.Name, field.Unit, detail.Unit))
+		}
+		if problem := checkMtrFieldValue(field, str.TrimSpace(detail.Value)); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("material certificate %s is incomplete for %s: %s", materialCert.TrackingId, materialCert.MaterialGroup, str.Join(problems, "; "))
+	}
+	return nil
+}
+
+func getMtrTemplate(stub shim.ChaincodeStubInterface, materialGroup string) (MtrTemplate, bool) {
+	template := MtrTemplate{}
+	templateKey, err := stub.CreateCompositeKey(DOC_TYPE_MTR_TEMPLATE, []string{str.ToLower(materialGroup)})
+	if err != nil {
+		return template, false
+	}
+	templateBytes, err := stub.GetState(templateKey)
+	if err != nil || templateBytes == nil {
+		return template, false
+	}
+	json.Unmarshal(templateBytes, &template)
+	return template, true
+}
+
+func checkMtrFieldValue(field MtrTemplateField, value string) string {
+	switch field.Type {
+	case MTR_FIELD_NUMBER:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return field.Name + " must be a number"
+		}
+		if field.Min != nil && number < *field.Min {
+			return fmt.Sprintf("%s must be at least %v", field.Name, *field.Min)
+		}
+		if field.Max != nil && number > *field.Max {
+			return fmt.Sprintf("%s must be at most %v", field.Name, *field.Max)
+		}
+	case MTR_FIELD_BOOLEAN:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return field.Name + " must be true or false"
+		}
+	case MTR_FIELD_DATE:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return field.Name + " must be a date"
+			}
+		}
+	}
+	return ""
+}
+
+/*
+	Field names are matched ignoring case, spaces and underscores so "Heat Number" matches "heatNumber"
+*/
+func mtrFieldKey(name string) string {
+	return str.ToLower(str.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+}
@@ -11,6 +11,12 @@ const (
 	MODE_MANUFACTURER_ACK    = "manufactureracknowledge"
 	MODE_ITEM_SHIPPED        = "itemshipped"
 	MODE_ITEM_DELIVERED      = "delivered"
+	MODE_ITEM_RETURNED       = "itemreturned"
+	MODE_WAREHOUSE           = "warehouse"
+	MODE_MTR_LINKED          = "mtrlinked"
+	MODE_MTR_REVISED         = "mtrrevised"
+	MODE_MTR_REVIEWED        = "mtrreviewed"
+	MODE_ATTESTATION         = "attestation"
 )
 
 /*
@@ -77,12 +83,29 @@ func updateSharedProgressRecord(stub shim.ChaincodeStubInterface, poId string, i
 				sharedProgress.LineItems[i].AssignedTo = lineItem.AssignedTo
 			case MODE_ITEM_SHIPPED:
 				sharedProgress.LineItems[i].MaterialCertificate = lineItem.MaterialCertificate
+				sharedProgress.LineItems[i].Compliance = lineItem.Compliance
 				sharedProgress.LineItems[i].TimeShipped = lineItem.TimeShipped
 				sharedProgress.LineItems[i].IotTrackingCode = lineItem.IotTrackingCode
 				sharedProgress.LineItems[i].ShippingRequestNumber = lineItem.ShippingRequestNumber
 			case MODE_ITEM_DELIVERED:
 				sharedProgress.LineItems[i].TimeReceived = lineItem.TimeReceived
 				sharedProgress.LineItems[i].IotProperties = lineItem.IotProperties
+			case MODE_WAREHOUSE:
+				sharedProgress.LineItems[i].Warehouse = lineItem.Warehouse
+			case MODE_MTR_LINKED:
+				for _, mtr := range lineItem.MaterialCertificate {
+					linked := false
+					for _, existing := range sharedProgress.LineItems[i].MaterialCertificate {
+						linked = linked || (existing.Name == mtr.Name && existing.Value == mtr.Value)
+					}
+					if !linked {
+						sharedProgress.LineItems[i].MaterialCertificate = append(sharedProgress.LineItems[i].MaterialCertificate, mtr)
+					}
+				}
+			case MODE_MTR_REVISED:
+				sharedProgress.LineItems[i].MtrReviewRequired = true
+			case MODE_MTR_REVIEWED:
+				sharedProgress.LineItems[i].MtrReviewRequired = false
 			}
 		}
 		lineItemBytes, err := json.Marshal(sharedProgress)
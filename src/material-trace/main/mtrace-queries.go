@@ -34,16 +34,14 @@ func (s *SmartContract) queryOpenOrderItems(stub shim.ChaincodeStubInterface, ar
 
 /*
 	Method: queryLogisticsOpenOrderItems
-	Returns all open order items for logistics operator screen
+	Returns the loads tendered or awarded to the calling carrier for the logistics operator screen
 */
 func (s *SmartContract) queryLogisticsOpenOrderItems(stub shim.ChaincodeStubInterface, args []string) sc.Response {
-	queryString := "{\"selector\":{\"lineItems\": {\"$gt\": null }}}"
-	collectionName := PRIVATE_COLLECTION_LOGISTICS
-	queryResults, err := getLogisticsPrivateDataQueryResults(stub, collectionName, queryString)
-	// getPrivateDataQueryResults
+	shippingRequestsResults, err := carrierLoads(stub)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
+	queryResults, _ := json.Marshal(shippingRequestsResults)
 	return shim.Success(queryResults)
 }
 
@@ -62,15 +60,38 @@ func (s *SmartContract) queryFieldOperatorItems(stub shim.ChaincodeStubInterface
 
 /*
 	Method: queryMtrItems
-	Returns a list of mtr records for either of the manufacturer
+	Returns the current mtr records of the manufacturer, or of both manufacturers for the distributor
 */
 func (s *SmartContract) queryMtrItems(stub shim.ChaincodeStubInterface, args []string) sc.Response {
-	queryString := "{\"selector\":{}}}"
-	collectionName := PRIVATE_COLLECTION_MTR_MFR1
-	if str.ToLower(currentMspId) == "org4msp" {
-		collectionName = PRIVATE_COLLECTION_MTR_MFR2
+	records := make([]MaterialCertificate, 0)
+	for _, collectionName := range mtrCollections(currentMspId) {
+		queryBytes, _ := json.Marshal(map[string]interface{}{"selector": mtrSelector(collectionName, MtrQuery{})})
+		queryResults, err := getMtrItemsResults(stub, collectionName, string(queryBytes))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		collectionRecords := make([]MaterialCertificate, 0)
+		json.Unmarshal(queryResults, &collectionRecords)
+		records = append(records, collectionRecords...)
+	}
+	recordBytes, _ := json.Marshal(records)
+	return shim.Success(recordBytes)
+}
+
+/*
+	Method: queryReturnAuthorizations
+	Returns the return authorizations visible to the current org.
+	Customer and distributor see all of them, manufacturers only those routed to them
+*/
+func (s *SmartContract) queryReturnAuthorizations(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	queryString := "{\"selector\":{\"docType\":\"" + DOC_TYPE_RETURN_AUTHORIZATION + "\"}}"
+	collectionName := PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR
+	if str.ToLower(currentMspId) == "org3msp" {
+		collectionName = PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER1
+	} else if str.ToLower(currentMspId) == "org4msp" {
+		collectionName = PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER2
 	}
-	queryResults, err := getMtrItemsResults(stub, collectionName, queryString)
+	queryResults, err := getRawPrivateDataQueryResults(stub, collectionName, queryString)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
@@ -667,14 +688,12 @@ func fillPoFromQueryResponse(stub shim.ChaincodeStubInterface, responseValue []b
 				}
 			}
 		}
-		poPrivateDataResponse, err1 = stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, po.PoId)
+		shippingRequests, err1 := getShippingRequestsForPo(stub, po.PoId)
 		if err1 != nil {
 			// continue
 
 		} else {
-			if poPrivateDataResponse != nil {
-				privateData := ShippingRequest{}
-				json.Unmarshal(poPrivateDataResponse, &privateData)
+			for _, privateData := range shippingRequests {
 				for _, shippingInfo := range privateData.LineItems {
 					index, found := indexByLineNumberMap[shippingInfo.LineNumber]
 					if !found {
@@ -770,57 +789,31 @@ func getMtrItemsResults(stub shim.ChaincodeStubInterface, privateCollectionName
 	buffer.WriteString("]")
 	return buffer.Bytes(), nil
 }
-func getLogisticsPrivateDataQueryResults(stub shim.ChaincodeStubInterface, privateCollectionName string, queryString string) ([]byte, error) {
-	fmt.Printf("- collection %s getQueryResultForQueryString queryString: \n%s\n", privateCollectionName, queryString)
+
+/*
+	Utility method that returns the documents matching a query in a private collection as a JSON array
+*/
+func getRawPrivateDataQueryResults(stub shim.ChaincodeStubInterface, privateCollectionName string, queryString string) ([]byte, error) {
 	resultsIterator, err := stub.GetPrivateDataQueryResult(privateCollectionName, queryString)
 	if err != nil {
 		return nil, err
 	}
 	defer resultsIterator.Close()
-
-	// buffer is a JSON array containing QueryRecords
-	shippingRequestsResults := make([]ShippingRequestsResults, 1)
-	prCount := 0
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+	bArrayMemberAlreadyWritten := false
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
-		shippingPd := ShippingPrivateDetails{}
-		json.Unmarshal(queryResponse.Value, &shippingPd)
-		srr := ShippingRequestsResults{}
-
-		if value, err := stub.GetState(shippingPd.PoId); err == nil && value != nil {
-			po := PurchaseOrder{}
-			json.Unmarshal(value, &po)
-			srr.PoId = po.PoId
-			srr.PoNumber = po.PoNumber
-			srr.Owner = po.Owner
-			srr.LineItems = shippingPd.LineItems
-			srr.ExpectedDeliveryDate = po.ExpectedDeliveryDate
-			srr.PoStatus = po.PoStatus
-			if prCount == 0 {
-				shippingRequestsResults[0] = srr
-			} else {
-				shippingRequestsResults = append(shippingRequestsResults, srr)
-			}
-			prCount += 1
-		}
-	}
-	var buffer bytes.Buffer
-	buffer.WriteString("[")
-	bArrayMemberAlreadyWritten := false
-	for _, item := range shippingRequestsResults {
-		// Add a comma before array members, suppress it for the first array member
 		if bArrayMemberAlreadyWritten == true {
 			buffer.WriteString(",")
 		}
-		resultAsBytes, _ := json.Marshal(item)
-		buffer.WriteString(string(resultAsBytes))
+		buffer.WriteString(string(queryResponse.Value))
 		bArrayMemberAlreadyWritten = true
 	}
 	buffer.WriteString("]")
-
 	return buffer.Bytes(), nil
 }
 func getFieldOperatorResultForQueryString(stub shim.ChaincodeStubInterface, queryString string) ([]byte, error) {
@@ -867,6 +860,11 @@ func getFieldOperatorResultForQueryString(stub shim.ChaincodeStubInterface, quer
 			pmview.PoNumber = po.PoNumber
 			pmview.LineItems = filteredLineItems
 			pmview.PoStatus = po.PoStatus
+			pmview.ProjectId = po.ProjectId
+			if pmview.ProjectId == "" {
+				pmview.ProjectId = filteredLineItems[0].ProjectId
+			}
+			pmview.ProjectBudget, _ = getProjectBudgetSummary(stub, pmview.ProjectId)
 			if prCount == 0 {
 				pmViewResults[0] = pmview
 			} else {
@@ -966,8 +964,6 @@ func getMtrQueryResults(stub shim.ChaincodeStubInterface, privateCollectionName
 		if err != nil {
 			return nil, err
 		}
-		mtr := MaterialCertificate{}
-		json.Unmarshal(queryResponse.Value, &mtr)
 
 		// Add comma before array members,suppress it for the first array member
 		if bArrayMemberAlreadyWritten == true {
@@ -980,9 +976,7 @@ func getMtrQueryResults(stub shim.ChaincodeStubInterface, privateCollectionName
 
 		buffer.WriteString(", \"Record\":")
 		// Record is a JSON object, so we write as-is
-		mtrBytes, _ := json.Marshal(mtr)
-		buffer.WriteString(string(mtrBytes))
-		// buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString(string(queryResponse.Value))
 		buffer.WriteString("}")
 		bArrayMemberAlreadyWritten = true
 
@@ -1009,8 +1003,6 @@ func getLineItemQueryResults(stub shim.ChaincodeStubInterface, privateCollection
 		if err != nil {
 			return nil, err
 		}
-		lineItemDetails := LineItemPrivateDetails{}
-		json.Unmarshal(queryResponse.Value, &lineItemDetails)
 
 		// Add comma before array members,suppress it for the first array member
 		if bArrayMemberAlreadyWritten == true {
@@ -1022,10 +1014,8 @@ func getLineItemQueryResults(stub shim.ChaincodeStubInterface, privateCollection
 		buffer.WriteString("\"")
 
 		buffer.WriteString(", \"Record\":")
-		liBytes, _ := json.Marshal(lineItemDetails)
-		buffer.WriteString(string(liBytes))
 		// Record is a JSON object, so we write as-is
-		// buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString(string(queryResponse.Value))
 		buffer.WriteString("}")
 		bArrayMemberAlreadyWritten = true
 
@@ -1156,6 +1146,11 @@ func getFieldOperatorList(stub shim.ChaincodeStubInterface) ([]FieldOperatorRepo
 						itemCount += 1
 					}
 				}
+				pmView.ProjectId = reportItem.OriginalPo.ProjectId
+				if pmView.ProjectId == "" && len(privateData.LineItems) > 0 {
+					pmView.ProjectId = privateData.LineItems[0].ProjectId
+				}
+				pmView.ProjectBudget, _ = getProjectBudgetSummary(stub, pmView.ProjectId)
 				reportItem.PmViewItem = pmView
 				if poCount == 0 {
 					foReportItems[0] = reportItem
@@ -1244,35 +1239,37 @@ func getSpecificLineItemForACollection(stub shim.ChaincodeStubInterface, poId st
 
 /*
 	Method: shippedItemsList
-	A helper method that returns all items in logistics collection
+	A helper method that returns all shipping request lines in shipping collection grouped by PO
 */
 func shippedItemsList(stub shim.ChaincodeStubInterface) ([]ShippingRequestsResults, error) {
-	queryString := "{\"selector\":{\"lineItems\": {\"$gt\": null }}}"
-	privateCollectionName := PRIVATE_COLLECTION_LOGISTICS
-	fmt.Printf("- collection %s getQueryResultForQueryString queryString: \n%s\n", privateCollectionName, queryString)
-	resultsIterator, err := stub.GetPrivateDataQueryResult(privateCollectionName, queryString)
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_SHIPPING, DOC_TYPE_SHIPPING_REQUEST, []string{})
 	if err != nil {
 		return nil, err
 	}
 	defer resultsIterator.Close()
 	shippingRequestsResults := make([]ShippingRequestsResults, 1)
+	indexByPoId := make(map[string]int)
 	prCount := 0
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
-		shippingPd := ShippingPrivateDetails{}
-		json.Unmarshal(queryResponse.Value, &shippingPd)
+		shippingRequest := ShippingRequest{}
+		json.Unmarshal(queryResponse.Value, &shippingRequest)
+		if index, found := indexByPoId[shippingRequest.PoId]; found {
+			shippingRequestsResults[index].LineItems = append(shippingRequestsResults[index].LineItems, shippingRequest.LineItems...)
+			continue
+		}
 		srr := ShippingRequestsResults{}
 
-		if value, err := stub.GetState(shippingPd.PoId); err == nil && value != nil {
+		if value, err := stub.GetState(shippingRequest.PoId); err == nil && value != nil {
 			po := PurchaseOrder{}
 			json.Unmarshal(value, &po)
 			srr.PoId = po.PoId
 			srr.PoNumber = po.PoNumber
 			srr.Owner = po.Owner
-			srr.LineItems = shippingPd.LineItems
+			srr.LineItems = shippingRequest.LineItems
 			srr.ExpectedDeliveryDate = po.ExpectedDeliveryDate
 			srr.PoStatus = po.PoStatus
 			if prCount == 0 {
@@ -1280,6 +1277,7 @@ func shippedItemsList(stub shim.ChaincodeStubInterface) ([]ShippingRequestsResul
 			} else {
 				shippingRequestsResults = append(shippingRequestsResults, srr)
 			}
+			indexByPoId[shippingRequest.PoId] = prCount
 			prCount += 1
 		}
 	}
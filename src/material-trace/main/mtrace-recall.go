@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_RECALL      = "recall"
+	DOC_TYPE_RECALL_HOLD = "recallHold"
+)
+
+/*
+	Method: openRecall
+	Executed when a manufacturer or the customer flags a material certificate or heat as suspect.
+	Affected lines are found through the genealogy, undelivered units are put on hold and
+	a recall event is emitted naming every affected organization.
+	Recalls and holds live in the logistics collection so every organization that ships or receives can read them.
+*/
+func (s *SmartContract) openRecall(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. recall")
+	}
+	recall := Recall{}
+	err := json.Unmarshal([]byte(args[0]), &recall)
+	if err != nil {
+		return shim.Error("Unable to parse recall data provided - " + args[0])
+	}
+	if recall.RecallId == "" || (recall.TrackingId == "" && recall.HeatNumber == "") || recall.Reason == "" {
+		return shim.Error("recallId, reason and a trackingId or heatNumber are required.")
+	}
+	if _, found := getRecall(stub, recall.RecallId); found {
+		return shim.Error("Recall already exists - " + recall.RecallId)
+	}
+	// find affected line items, shipments and projects
+	collections := genealogyCollections(currentMspId)
+	edges := make([]GenealogyEdge, 0)
+	if recall.HeatNumber != "" {
+		edges = append(edges, walkGenealogy(stub, collections, GENEALOGY_NODE_HEAT, recall.HeatNumber, GENEALOGY_FORWARD, GENEALOGY_MAX_DEPTH)...)
+	}
+	if recall.TrackingId != "" {
+		edges = append(edges, walkGenealogy(stub, collections, GENEALOGY_NODE_MTR, recall.TrackingId, GENEALOGY_FORWARD, GENEALOGY_MAX_DEPTH)...)
+	}
+	itemKeys := make(map[string]bool)
+	shipments := make(map[string]bool)
+	projects := make(map[string]bool)
+	orgs := map[string]bool{organizationMap["org1msp"]: true, organizationMap["org2msp"]: true}
+	for _, edge := range edges {
+		switch edge.ToType {
+		case GENEALOGY_NODE_ORDER_REQUEST:
+			separator := str.LastIndex(edge.ToId, "|")
+			if separator > 0 {
+				itemKeys[edge.ToId[:separator]] = true
+				orgs[edge.ToId[separator+1:]] = true
+			}
+		case GENEALOGY_NODE_SHIPMENT:
+			shipments[edge.ToId] = true
+			if carrier := shipmentCarrier(stub, edge.ToId); carrier != "" {
+				orgs[carrier] = true
+			}
+		case GENEALOGY_NODE_PO_LINE:
+			itemKeys[edge.ToId] = true
+		case GENEALOGY_NODE_PROJECT:
+			projects[edge.ToId] = true
+		}
+	}
+	recall.ObjectType = DOC_TYPE_RECALL
+	recall.Status = STATUS_OPEN
+	recall.InitiatedBy = organizationMap[currentMspId]
+	recall.AffectedItemKeys = sortedKeys(itemKeys)
+	recall.AffectedShipments = sortedKeys(shipments)
+	recall.AffectedProjects = sortedKeys(projects)
+	recall.AffectedOrgs = sortedKeys(orgs)
+	recall.HeldItemKeys = make([]string, 0)
+	recall.Actions = make([]RecallAction, 0)
+
+	// hold the lines that still have suspect material in the supply chain
+	holdStatus := ItemStatus{Owner: recall.InitiatedBy, Status: STATUS_ON_HOLD, TimeStamp: recall.CreatedTimeStamp}
+	for _, itemKey := range recall.AffectedItemKeys {
+		poId := str.Split(itemKey, "|")[0]
+		units, _ := getSerializedUnits(stub, poId, itemKey)
+		undelivered := len(units) == 0
+		for _, unit := range units {
+			// only compare the identifiers the recall names, units without one never match
+			heatMatches := recall.HeatNumber != "" && unit.HeatNumber == recall.HeatNumber
+			trackingIdMatches := recall.TrackingId != "" && unit.TrackingId == recall.TrackingId
+			if !heatMatches && !trackingIdMatches {
+				continue
+			}
+			if unit.Status == STATUS_RECEIVED || unit.Status == STATUS_INSTALLED {
+				continue
+			}
+			undelivered = true
+			unit.OnHold = true
+			unit.RecallId = recall.RecallId
+			unit.ProgressStatus = append(unit.ProgressStatus, holdStatus)
+			if err := commitSerializedUnit(stub, unit); err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+		if !undelivered {
+			continue
+		}
+		err = putRecallHold(stub, recall.RecallId, poId, itemKey)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		recall.HeldItemKeys = append(recall.HeldItemKeys, itemKey)
+	}
+	recallBytes, err := commitRecall(stub, recall)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setRecallEvent(stub, recall, "Material recall opened")
+	return shim.Success(recallBytes)
+}
+
+/*
+	Method: recordRecallAction
+	Executed when an affected organization records a follow-up action on an open recall
+*/
+func (s *SmartContract) recordRecallAction(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. recallId 2. action")
+	}
+	recall, found := getRecall(stub, args[0])
+	if !found {
+		return shim.Error("Recall not found - " + args[0])
+	}
+	if recall.Status != STATUS_OPEN {
+		return shim.Error("Recall is closed - " + args[0])
+	}
+	action := RecallAction{}
+	err := json.Unmarshal([]byte(args[1]), &action)
+	if err != nil || action.Action == "" {
+		return shim.Error("Unable to parse recall action provided - " + args[1])
+	}
+	action.Organization = organizationMap[currentMspId]
+	if !containsFold(recall.AffectedOrgs, action.Organization) {
+		return shim.Error(action.Organization + " is not affected by recall " + recall.RecallId)
+	}
+	recall.Actions = append(recall.Actions, action)
+	recallBytes, err := commitRecall(stub, recall)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(recallBytes)
+}
+
+/*
+	Method: closeRecall
+	Executed by the initiator or the customer once every affected organization has followed up.
+	Holds are released so the remaining material can move again.
+*/
+func (s *SmartContract) closeRecall(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. recallId 2. resolution 3. timeStamp")
+	}
+	closedTimeStamp, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[2] + " Expecting a number.")
+	}
+	recall, found := getRecall(stub, args[0])
+	if !found {
+		return shim.Error("Recall not found - " + args[0])
+	}
+	if recall.Status != STATUS_OPEN {
+		return shim.Error("Recall is already closed - " + args[0])
+	}
+	closedBy := organizationMap[currentMspId]
+	if closedBy != recall.InitiatedBy && currentMspId != "org1msp" {
+		return shim.Error("Only the initiator or the customer can close recall " + recall.RecallId)
+	}
+	pending := make([]string, 0)
+	for _, org := range recall.AffectedOrgs {
+		followedUp := false
+		for _, action := range recall.Actions {
+			if str.EqualFold(action.Organization, org) {
+				followedUp = true
+				break
+			}
+		}
+		if !followedUp {
+			pending = append(pending, org)
+		}
+	}
+	if len(pending) > 0 {
+		return shim.Error("Follow-up actions pending from: " + str.Join(pending, ", "))
+	}
+	releaseStatus := ItemStatus{Owner: closedBy, Status: STATUS_HOLD_RELEASED, TimeStamp: closedTimeStamp}
+	for _, itemKey := range recall.HeldItemKeys {
+		poId := str.Split(itemKey, "|")[0]
+		holdKey, err := recallHoldKey(stub, poId, itemKey, recall.RecallId)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.DelPrivateData(PRIVATE_COLLECTION_LOGISTICS, holdKey)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		units, _ := getSerializedUnits(stub, poId, itemKey)
+		for _, unit := range units {
+			if unit.RecallId != recall.RecallId || !unit.OnHold {
+				continue
+			}
+			unit.OnHold = false
+			unit.ProgressStatus = append(unit.ProgressStatus, releaseStatus)
+			if err := commitSerializedUnit(stub, unit); err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+	}
+	recall.Status = STATUS_CLOSED
+	recall.Resolution = args[1]
+	recall.ClosedTimeStamp = closedTimeStamp
+	recallBytes, err := commitRecall(stub, recall)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setRecallEvent(stub, recall, "Material recall closed")
+	return shim.Success(recallBytes)
+}
+
+/*
+	Method: queryRecalls
+	Returns recalls, optionally filtered by status
+*/
+func (s *SmartContract) queryRecalls(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	status := ""
+	if len(args) > 0 {
+		status = args[0]
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_LOGISTICS, DOC_TYPE_RECALL, []string{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	recalls := make([]Recall, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		recall := Recall{}
+		json.Unmarshal(queryResponse.Value, &recall)
+		if status != "" && recall.Status != status {
+			continue
+		}
+		recalls = append(recalls, recall)
+	}
+	recallBytes, _ := json.Marshal(recalls)
+	return shim.Success(recallBytes)
+}
+
+/*
+	Method: checkRecallHolds
+	Returns an error naming the recall when any of the given lines of a PO is on hold
+*/
+func checkRecallHolds(stub shim.ChaincodeStubInterface, poId string, lineNumbers []int) error {
+	for _, lineNumber := range lineNumbers {
+		resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_LOGISTICS, DOC_TYPE_RECALL_HOLD, []string{poId, strconv.Itoa(lineNumber)})
+		if err != nil {
+			return err
+		}
+		if resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			resultsIterator.Close()
+			if err != nil {
+				return err
+			}
+			hold := RecallHold{}
+			json.Unmarshal(queryResponse.Value, &hold)
+			return fmt.Errorf("PO %s line %d is on hold under recall %s", poId, lineNumber, hold.RecallId)
+		}
+		resultsIterator.Close()
+	}
+	return nil
+}
+
+func putRecallHold(stub shim.ChaincodeStubInterface, recallId string, poId string, itemKey string) error {
+	holdKey, err := recallHoldKey(stub, poId, itemKey, recallId)
+	if err != nil {
+		return err
+	}
+	lineNumber, _ := strconv.Atoi(str.Split(itemKey, "|")[1])
+	hold := RecallHold{ObjectType: DOC_TYPE_RECALL_HOLD, RecallId: recallId, PoId: poId, LineNumber: lineNumber, ItemKey: itemKey}
+	holdBytes, _ := json.Marshal(hold)
+	return stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, holdKey, holdBytes)
+}
+
+/*
+	Hold keys are by PO and line number, shipping line items don't carry the item key
+*/
+func recallHoldKey(stub shim.ChaincodeStubInterface, poId string, itemKey string, recallId string) (string, error) {
+	parts := str.Split(itemKey, "|")
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid item key %s", itemKey)
+	}
+	return stub.CreateCompositeKey(DOC_TYPE_RECALL_HOLD, []string{poId, parts[1], recallId})
+}
+
+func getRecall(stub shim.ChaincodeStubInterface, recallId string) (Recall, bool) {
+	recall := Recall{}
+	recallKey, err := stub.CreateCompositeKey(DOC_TYPE_RECALL, []string{recallId})
+	if err != nil {
+		return recall, false
+	}
+	recallBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_LOGISTICS, recallKey)
+	if err != nil || recallBytes == nil {
+		return recall, false
+	}
+	json.Unmarshal(recallBytes, &recall)
+	return recall, true
+}
+
+func commitRecall(stub shim.ChaincodeStubInterface, recall Recall) ([]byte, error) {
+	recallKey, err := stub.CreateCompositeKey(DOC_TYPE_RECALL, []string{recall.RecallId})
+	if err != nil {
+		return nil, err
+	}
+	recallBytes, err := json.Marshal(recall)
+	if err != nil {
+		return nil, err
+	}
+	return recallBytes, stub.PutPrivateData(PRIVATE_COLLECTION_LOGISTICS, recallKey, recallBytes)
+}
+
+func setRecallEvent(stub shim.ChaincodeStubInterface, recall Recall, description string) {
+	var event = RecallEvent{Type: "recall", Description: description, Status: recall.Status, RecallId: recall.RecallId, TrackingId: recall.TrackingId, HeatNumber: recall.HeatNumber, AffectedOrgs: recall.AffectedOrgs, AffectedItemKeys: recall.AffectedItemKeys}
+	eventBytes, err := json.Marshal(&event)
+	if err != nil {
+		fmt.Println("unable to marshal event ", err)
+	}
+	err = stub.SetEvent(event.Type, eventBytes)
+	if err != nil {
+		fmt.Println("Could not set event for recall ", err)
+	} else {
+		logger.Infof("Event set - type: %s description: %s", event.Type, event.Description)
+	}
+}
+
+func sortedKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
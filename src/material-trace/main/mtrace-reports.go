@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_PURCHASE_ORDER = "purchaseOrder"
+)
+
+/*
+	Method: queryMarginReport
+	Returns revenue, cost, margin and margin% per PO, project, manufacturer and material group
+	for accepted purchase orders created within a date range.
+	Revenue comes from the customer line items, cost from the prices agreed with each manufacturer.
+	Lines fulfilled from inventory carry no manufacturer cost and are reported under "Inventory".
+	Purchase orders are selected by docType, status and creation time, see indexes under META-INF.
+*/
+func (s *SmartContract) queryMarginReport(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. from timestamp 2. to timestamp (0 for no upper bound)")
+	}
+	fromTimeStamp, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[0] + " Expecting a number.")
+	}
+	toTimeStamp, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[1] + " Expecting a number.")
+	}
+	createdTimeStamp := map[string]interface{}{"$gte": fromTimeStamp}
+	if toTimeStamp > 0 {
+		createdTimeStamp["$lte"] = toTimeStamp
+	}
+	queryBytes, _ := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"docType":          DOC_TYPE_PURCHASE_ORDER,
+			"poStatus":         STATUS_ACCEPTED,
+			"createdTimeStamp": createdTimeStamp,
+		},
+	})
+	resultsIterator, err := stub.GetQueryResult(string(queryBytes))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+
+	total := MarginSummary{Key: "total"}
+	byPo := make(map[string]*MarginSummary)
+	byProject := make(map[string]*MarginSummary)
+	byManufacturer := make(map[string]*MarginSummary)
+	byMaterialGroup := make(map[string]*MarginSummary)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		po := PurchaseOrder{}
+		json.Unmarshal(queryResponse.Value, &po)
+		if po.PoId == "" {
+			continue
+		}
+		poPrivateDataResponse, err1 := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, po.PoId)
+		if err1 != nil || poPrivateDataResponse == nil {
+			logger.Infof("Unable to get %s data for PO: %s ", PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, po.PoId)
+			continue
+		}
+		pLineItem := LineItemPrivateDetails{}
+		json.Unmarshal(poPrivateDataResponse, &pLineItem)
+		mfrPricing := make(map[string][]LineItemPricing)
+		for _, collectionName := range []string{PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER1, PRIVATE_COLLECTION_DISTRIBUTOR_MANUFACTURER2} {
+			pricingResponse, err1 := stub.GetPrivateData(collectionName, po.PoId)
+			if err1 != nil || pricingResponse == nil {
+				continue
+			}
+			pricingData := LineItemCDPrivateDetails{}
+			json.Unmarshal(pricingResponse, &pricingData)
+			for _, priceInfo := range pricingData.LineItems {
+				mfrPricing[priceInfo.ItemKey] = append(mfrPricing[priceInfo.ItemKey], priceInfo)
+			}
+		}
+		poKey := strconv.Itoa(po.PoNumber)
+		for _, lineItem := range pLineItem.LineItems {
+			revenue := lineItem.Subtotal
+			if revenue == 0 {
+				revenue = math.Round(float64(lineItem.Quantity) * lineItem.UnitPrice)
+			}
+			cost := 0.0
+			manufacturer := "Inventory"
+			for _, priceInfo := range mfrPricing[lineItem.ItemKey] {
+				manufacturer = priceInfo.AssignedTo
+				if priceInfo.Subtotal > 0 {
+					cost += priceInfo.Subtotal
+				} else {
+					cost += math.Round(float64(priceInfo.Quantity) * priceInfo.UnitPrice)
+				}
+			}
+			projectId := lineItem.ProjectId
+			if projectId == "" {
+				projectId = po.ProjectId
+			}
+			addToMarginSummary(&total, revenue, cost)
+			addToMarginGroup(byPo, poKey, revenue, cost)
+			addToMarginGroup(byProject, projectId, revenue, cost)
+			addToMarginGroup(byManufacturer, manufacturer, revenue, cost)
+			addToMarginGroup(byMaterialGroup, lineItem.MaterialGroup, revenue, cost)
+		}
+	}
+	report := MarginReport{
+		FromTimeStamp:   fromTimeStamp,
+		ToTimeStamp:     toTimeStamp,
+		Currency:        DEFAULT_CURRENCY,
+		Total:           finalizeMarginSummary(total),
+		ByPo:            sortedMarginSummaries(byPo),
+		ByProject:       sortedMarginSummaries(byProject),
+		ByManufacturer:  sortedMarginSummaries(byManufacturer),
+		ByMaterialGroup: sortedMarginSummaries(byMaterialGroup),
+	}
+	reportBytes, _ := json.Marshal(report)
+	return shim.Success(reportBytes)
+}
+
+/*
+	Method: addToMarginGroup
+	Adds revenue and cost to the summary for a key, creating it on first use
+*/
+func addToMarginGroup(group map[string]*MarginSummary, key string, revenue float64, cost float64) {
+	summary, found := group[key]
+	if !found {
+		summary = &MarginSummary{Key: key}
+		group[key] = summary
+	}
+	addToMarginSummary(summary, revenue, cost)
+}
+
+func addToMarginSummary(summary *MarginSummary, revenue float64, cost float64) {
+	summary.Revenue += revenue
+	summary.Cost += cost
+}
+
+/*
+	Method: finalizeMarginSummary
+	Calculates margin and margin% once all revenue and cost has been added
+*/
+func finalizeMarginSummary(summary MarginSummary) MarginSummary {
+	summary.Margin = summary.Revenue - summary.Cost
+	if summary.Revenue != 0 {
+		summary.MarginPercent = math.Round(summary.Margin/summary.Revenue*10000) / 100
+	}
+	return summary
+}
+
+/*
+	Method: sortedMarginSummaries
+	Returns the summaries of a group ordered by key so every peer endorses the same result
+*/
+func sortedMarginSummaries(group map[string]*MarginSummary) []MarginSummary {
+	keys := make([]string, 0, len(group))
+	for key := range group {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	summaries := make([]MarginSummary, 0, len(keys))
+	for _, key := range keys {
+		summaries = append(summaries, finalizeMarginSummary(*group[key]))
+	}
+	return summaries
+}
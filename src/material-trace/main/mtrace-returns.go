@@ -0,0 +1,500 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_RETURN_AUTHORIZATION = "returnAuthorization"
+	RMA_RESOLUTION_REPLACEMENT    = "replacement"
+	RMA_RESOLUTION_CREDIT         = "credit"
+)
+
+/*
+	Method: openReturnAuthorization
+	Executed when the customer opens an RMA for specific lines and quantities of a received purchase order.
+	The RMA is stored in the collection shared by customer and distributor and every returned line
+	gets a return-requested entry on its progress timeline.
+*/
+func (s *SmartContract) openReturnAuthorization(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. return authorization 2. progress status")
+	}
+	rma := ReturnAuthorization{}
+	err := json.Unmarshal([]byte(args[0]), &rma)
+	if err != nil {
+		return shim.Error("Unable to parse return authorization data provided - " + args[0])
+	}
+	progressStatus := ItemStatus{}
+	err = json.Unmarshal([]byte(args[1]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[1])
+	}
+	if rma.RmaId == "" || rma.PoId == "" {
+		return shim.Error("rmaId and poId are required.")
+	}
+	if len(rma.ReturnLines) == 0 {
+		return shim.Error("At least one return line is required.")
+	}
+	rmaKey, err := stub.CreateCompositeKey(DOC_TYPE_RETURN_AUTHORIZATION, []string{rma.RmaId})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if value, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR, rmaKey); !(err == nil && value == nil) {
+		return Error(http.StatusConflict, fmt.Sprintf("return authorization with id %s exists", rma.RmaId))
+	}
+	poPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, rma.PoId)
+	if err != nil || poPrivateDataResponse == nil {
+		return shim.Error("private lineItems data not found for " + rma.PoId)
+	}
+	pLineItem := LineItemPrivateDetails{}
+	json.Unmarshal(poPrivateDataResponse, &pLineItem)
+	indexByLineNumberMap := make(map[int]int)
+	for i, lineItem := range pLineItem.LineItems {
+		indexByLineNumberMap[lineItem.LineNumber] = i
+	}
+	for i, returnLine := range rma.ReturnLines {
+		index, found := indexByLineNumberMap[returnLine.LineNumber]
+		if !found {
+			return shim.Error("Line number " + strconv.Itoa(returnLine.LineNumber) + " not found on PO " + rma.PoId)
+		}
+		lineItem := pLineItem.LineItems[index]
+		if lineItem.Status != STATUS_DELIVERED && lineItem.Status != STATUS_RECEIVED && lineItem.Status != STATUS_VERIFIED {
+			return shim.Error("Line number " + strconv.Itoa(lineItem.LineNumber) + " has status " + lineItem.Status + "; only delivered, received or verified items can be returned.")
+		}
+		// earlier RMAs on the same line reduce what is left to return
+		if returnLine.Quantity <= 0 || returnLine.Quantity > lineItem.Quantity-lineItem.ReturnedQuantity {
+			return shim.Error("Invalid return quantity for line number " + strconv.Itoa(lineItem.LineNumber) + "; " + strconv.Itoa(lineItem.Quantity-lineItem.ReturnedQuantity) + " left to return")
+		}
+		fulfilledBy := lineItem.AssignedTo
+		if len(lineItem.OrderRequests) > 0 {
+			fulfilledBy = lineItem.OrderRequests[0].FulfilledBy
+		}
+		if i == 0 {
+			rma.Manufacturer = fulfilledBy
+		} else if str.ToLower(rma.Manufacturer) != str.ToLower(fulfilledBy) {
+			return shim.Error("All returned lines must be supplied by the same party; open a separate RMA for " + fulfilledBy)
+		}
+		rma.ReturnLines[i].ItemKey = lineItem.ItemKey
+		rma.ReturnLines[i].MaterialId = lineItem.MaterialId
+		rma.ReturnLines[i].Description = lineItem.Description
+		rma.ReturnLines[i].UnitOfMeasure = lineItem.UnitOfMeasure
+		rma.ReturnLines[i].UnitPrice = lineItem.UnitPrice
+		rma.ReturnLines[i].FulfilledBy = fulfilledBy
+		if returnLine.Reason == "" {
+			rma.ReturnLines[i].Reason = rma.Reason
+		}
+		rma.PoNumber = lineItem.PoNumber
+	}
+	progressStatus.Status = STATUS_RETURN_REQUESTED
+	rma.ObjectType = DOC_TYPE_RETURN_AUTHORIZATION
+	rma.Status = STATUS_RETURN_REQUESTED
+	rma.RequestedBy = organizationMap["org1msp"]
+	rma.CreatedTimeStamp = progressStatus.TimeStamp
+	rma.ProgressStatus = []ItemStatus{progressStatus}
+
+	err = updateReturnLineItems(stub, rma, progressStatus, nil)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	rmaBytes, err := commitReturnAuthorization(stub, rma)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setReturnAuthorizationEvent(stub, "rmaopened", "Return Authorization Opened", rma, rma.RequestedBy)
+	return shim.Success(rmaBytes)
+}
+
+/*
+	Method: routeReturnAuthorization
+	Executed when the distributor authorizes an RMA and routes it back to the supplying manufacturer.
+	A copy of the RMA is added to the collection shared by the distributor and that manufacturer.
+	Items fulfilled from inventory stay with the distributor.
+*/
+func (s *SmartContract) routeReturnAuthorization(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. rmaId 2. progress status")
+	}
+	progressStatus := ItemStatus{}
+	err := json.Unmarshal([]byte(args[1]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[1])
+	}
+	rma, err := getReturnAuthorization(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if rma.Status != STATUS_RETURN_REQUESTED {
+		return shim.Error("Return authorization " + rma.RmaId + " has status " + rma.Status + "; expected " + STATUS_RETURN_REQUESTED)
+	}
+	progressStatus.Status = STATUS_RETURN_AUTHORIZED
+	rma.Status = STATUS_RETURN_AUTHORIZED
+	rma.ProgressStatus = append(rma.ProgressStatus, progressStatus)
+
+	err = updateReturnLineItems(stub, rma, progressStatus, nil)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	rmaBytes, err := commitReturnAuthorization(stub, rma)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setReturnAuthorizationEvent(stub, "rmarouted", "Return Authorization Routed", rma, rma.Manufacturer)
+	return shim.Success(rmaBytes)
+}
+
+/*
+	Method: requestReturnShipping
+	Executed by the distributor to have a carrier collect the returned material from the customer.
+	The return shipping lines are stored as a shipping request of the original PO, which the
+	distributor tenders to carriers like any other shipping request.
+*/
+func (s *SmartContract) requestReturnShipping(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 5. 1. rmaId 2. shippingRequestNumber 3. iotTrackingCode 4. return destination 5. progress status")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[1] + " Expecting an int64 number.")
+	}
+	iotTrackingCode := args[2]
+	returnDestination := Company{}
+	err = json.Unmarshal([]byte(args[3]), &returnDestination)
+	if err != nil {
+		return shim.Error("Unable to parse return destination provided - " + args[3])
+	}
+	progressStatus := ItemStatus{}
+	err = json.Unmarshal([]byte(args[4]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[4])
+	}
+	rma, err := getReturnAuthorization(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if rma.Status != STATUS_RETURN_AUTHORIZED {
+		return shim.Error("Return authorization " + rma.RmaId + " has status " + rma.Status + "; expected " + STATUS_RETURN_AUTHORIZED)
+	}
+	progressStatus.Status = STATUS_RETURN_SHIPPING
+	logisticsInitialStatus := []ItemStatus{progressStatus}
+
+	shippingRequest, err := startShippingRequest(stub, rma.PoId, shippingRequestNumber, organizationMap["org2msp"], Company{})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	for _, returnLine := range rma.ReturnLines {
+		lineItem := LineItem{
+			PoNumber:        rma.PoNumber,
+			LineNumber:      returnLine.LineNumber,
+			MaterialId:      returnLine.MaterialId,
+			Description:     returnLine.Description,
+			Quantity:        returnLine.Quantity,
+			UnitOfMeasure:   returnLine.UnitOfMeasure,
+			ShipToLocation:  returnDestination,
+			IotTrackingCode: iotTrackingCode,
+			TimeShipped:     progressStatus.TimeStamp,
+		}
+		shipLineItem := fillShippingLineItems(stub, rma.PoId, shippingRequestNumber, lineItem, organizationMap["org2msp"], logisticsInitialStatus, progressStatus)
+		shipLineItem.ItemKey = returnLine.ItemKey
+		shipLineItem.RmaId = rma.RmaId
+		addShippingLineItem(&shippingRequest, shipLineItem)
+	}
+	if err := commitShippingRequest(stub, shippingRequest); err != nil {
+		return shim.Error(err.Error())
+	}
+
+	rma.Status = STATUS_RETURN_SHIPPING
+	rma.ShippingRequestNumber = shippingRequestNumber
+	rma.IotTrackingCode = iotTrackingCode
+	rma.ProgressStatus = append(rma.ProgressStatus, progressStatus)
+	err = updateReturnLineItems(stub, rma, progressStatus, nil)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	rmaBytes, err := commitReturnAuthorization(stub, rma)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setReturnAuthorizationEvent(stub, "rmashippingrequested", "Return Shipping Requested", rma, organizationMap["org2msp"])
+	return shim.Success(rmaBytes)
+}
+
+/*
+	Method: resolveReturnAuthorization
+	Executed by the distributor to close an RMA with either a replacement order or a credit note.
+	A replacement adds a new open order request on each returned line and, for manufacturer
+	supplied items, a new order line in the distributor/manufacturer collection.
+*/
+func (s *SmartContract) resolveReturnAuthorization(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. rmaId 2. resolution (replacement|credit) 3. credit note id 4. progress status")
+	}
+	resolution := str.ToLower(args[1])
+	if resolution != RMA_RESOLUTION_REPLACEMENT && resolution != RMA_RESOLUTION_CREDIT {
+		return shim.Error("Expecting replacement or credit for second argument. Found: " + args[1])
+	}
+	if resolution == RMA_RESOLUTION_CREDIT && len(args[2]) == 0 {
+		return shim.Error("Credit note id is required.")
+	}
+	progressStatus := ItemStatus{}
+	err := json.Unmarshal([]byte(args[3]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[3])
+	}
+	rma, err := getReturnAuthorization(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if rma.Status != STATUS_RETURN_AUTHORIZED && rma.Status != STATUS_RETURN_SHIPPING {
+		return shim.Error("Return authorization " + rma.RmaId + " has status " + rma.Status + " and cannot be resolved.")
+	}
+	rma.Resolution = resolution
+	rma.ClosedTimeStamp = progressStatus.TimeStamp
+	var replacementOrders map[int]OrderRequest
+	if resolution == RMA_RESOLUTION_CREDIT {
+		progressStatus.Status = STATUS_CREDITED
+		amount := 0.0
+		for _, returnLine := range rma.ReturnLines {
+			amount += float64(returnLine.Quantity) * returnLine.UnitPrice
+		}
+		rma.CreditNote = CreditNote{CreditNoteId: args[2], Amount: math.Round(amount*100) / 100, Currency: DEFAULT_CURRENCY, TimeStamp: progressStatus.TimeStamp}
+	} else {
+		progressStatus.Status = STATUS_REPLACED
+		replacementOrders = make(map[int]OrderRequest)
+		rma.ReplacementOrders = make([]OrderRequest, 0)
+		for i, returnLine := range rma.ReturnLines {
+			rma.ReturnLines[i].ReplacementItemKey = generateReplacementItemKey(rma, returnLine)
+			orderRequest := OrderRequest{}
+			orderRequest.LineNumber = returnLine.LineNumber
+			orderRequest.MaterialId = returnLine.MaterialId
+			orderRequest.Quantity = returnLine.Quantity
+			orderRequest.Status = STATUS_OPEN
+			orderRequest.FulfilledBy = returnLine.FulfilledBy
+			orderRequest.AcknowledgedTimeStamp = progressStatus.TimeStamp
+			orderRequest.ProgressStatus = []ItemStatus{progressStatus}
+			replacementOrders[returnLine.LineNumber] = orderRequest
+			rma.ReplacementOrders = append(rma.ReplacementOrders, orderRequest)
+		}
+		err = addReplacementOrderLines(stub, rma, progressStatus)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	rma.Status = progressStatus.Status
+	rma.ProgressStatus = append(rma.ProgressStatus, progressStatus)
+	err = updateReturnLineItems(stub, rma, progressStatus, replacementOrders)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	rmaBytes, err := commitReturnAuthorization(stub, rma)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	setReturnAuthorizationEvent(stub, "rmaresolved", "Return Authorization Resolved", rma, organizationMap["org2msp"])
+	return shim.Success(rmaBytes)
+}
+
+/*
+	Method: addReplacementOrderLines
+	Adds a replacement order line for each returned line to the collection of the party
+	that supplied it, so it shows up with the open order requests of that party
+*/
+func addReplacementOrderLines(stub shim.ChaincodeStubInterface, rma ReturnAuthorization, progressStatus ItemStatus) error {
+	privateCollection := manufacturerCollection(rma.Manufacturer)
+	assignedTo := rma.Manufacturer
+	if privateCollection == "" {
+		privateCollection = PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR
+		assignedTo = "Inventory"
+	}
+	pricingData := LineItemCDPrivateDetails{}
+	privateDataResponse, err := stub.GetPrivateData(privateCollection, rma.PoId)
+	if err != nil {
+		return err
+	}
+	if privateDataResponse != nil {
+		json.Unmarshal(privateDataResponse, &pricingData)
+	} else {
+		pricingData.ObjectType = privateCollection
+		pricingData.PoId = rma.PoId
+	}
+	utilityInitialStatus := ItemStatus{
+		Owner:     organizationMap["org1msp"],
+		Status:    STATUS_OPEN,
+		TimeStamp: progressStatus.TimeStamp,
+	}
+	for _, returnLine := range rma.ReturnLines {
+		unitCost := returnLine.UnitPrice
+		lineItem := LineItem{
+			PoNumber:      rma.PoNumber,
+			LineNumber:    returnLine.LineNumber,
+			MaterialId:    returnLine.MaterialId,
+			ItemKey:       returnLine.ReplacementItemKey,
+			Description:   returnLine.Description,
+			UnitOfMeasure: returnLine.UnitOfMeasure,
+			Currency:      DEFAULT_CURRENCY,
+		}
+		for _, existing := range pricingData.LineItems {
+			if existing.ItemKey != returnLine.ItemKey {
+				continue
+			}
+			unitCost = existing.UnitPrice
+			lineItem.Currency = existing.Currency
+			lineItem.MaterialGroup = existing.MaterialGroup
+			lineItem.ShipToLocation = existing.ShipToLocation
+			lineItem.ProjectId = existing.ProjectId
+			break
+		}
+		pricingInfo := fillPricingInfo(stub, lineItem, "", assignedTo, returnLine.Quantity, unitCost, rma.PoNumber, rma.PoId, utilityInitialStatus, progressStatus)
+		pricingData.LineItems = append(pricingData.LineItems, pricingInfo)
+	}
+	pricingBytes, err := json.Marshal(pricingData)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(privateCollection, rma.PoId, pricingBytes)
+}
+
+/*
+	Method: generateReplacementItemKey
+	A replacement line keeps the line number of the returned line, so its key also carries the RMA id
+*/
+func generateReplacementItemKey(rma ReturnAuthorization, returnLine ReturnLineItem) string {
+	return str.Join([]string{returnLine.ItemKey, DOC_TYPE_RETURN_AUTHORIZATION, rma.RmaId}, "|")
+}
+
+/*
+	Method: updateReturnLineItems
+	Records an RMA step on the progress timeline of each returned line item in the
+	customer line items collection and in the shared progress record.
+	The returned quantity is added up when the RMA is opened; the line status only follows
+	the RMA once the whole line quantity is being returned.
+*/
+func updateReturnLineItems(stub shim.ChaincodeStubInterface, rma ReturnAuthorization, progressStatus ItemStatus, replacementOrders map[int]OrderRequest) error {
+	poPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, rma.PoId)
+	if err != nil {
+		return err
+	}
+	if poPrivateDataResponse == nil {
+		return fmt.Errorf("private lineItems data not found for %s", rma.PoId)
+	}
+	returnLineMap := make(map[int]ReturnLineItem)
+	for _, returnLine := range rma.ReturnLines {
+		returnLineMap[returnLine.LineNumber] = returnLine
+	}
+	itemPrivateData := LineItemPrivateDetails{}
+	json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
+	sharedItemsMap := make(map[int]LineItem)
+	for i, eachItem := range itemPrivateData.LineItems {
+		returnLine, found := returnLineMap[eachItem.LineNumber]
+		if !found {
+			continue
+		}
+		if progressStatus.Status == STATUS_RETURN_REQUESTED {
+			itemPrivateData.LineItems[i].ReturnedQuantity += returnLine.Quantity
+		}
+		if itemPrivateData.LineItems[i].ReturnedQuantity >= eachItem.Quantity {
+			itemPrivateData.LineItems[i].Status = progressStatus.Status
+		}
+		itemPrivateData.LineItems[i].ProgressStatus = append(itemPrivateData.LineItems[i].ProgressStatus, progressStatus)
+		if orderRequest, found := replacementOrders[eachItem.LineNumber]; found {
+			itemPrivateData.LineItems[i].OrderRequests = append(itemPrivateData.LineItems[i].OrderRequests, orderRequest)
+		}
+		sharedItemsMap[eachItem.LineNumber] = itemPrivateData.LineItems[i]
+	}
+	pdLineItemBytes, err := json.Marshal(itemPrivateData)
+	if err != nil {
+		return err
+	}
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, rma.PoId, pdLineItemBytes)
+	if err != nil {
+		return err
+	}
+	updateSharedProgressRecord(stub, rma.PoId, sharedItemsMap, progressStatus, MODE_ITEM_RETURNED)
+	return nil
+}
+
+/*
+	Method: getReturnAuthorization
+	Returns the RMA stored in the collection shared by customer and distributor
+*/
+func getReturnAuthorization(stub shim.ChaincodeStubInterface, rmaId string) (ReturnAuthorization, error) {
+	rma := ReturnAuthorization{}
+	rmaKey, err := stub.CreateCompositeKey(DOC_TYPE_RETURN_AUTHORIZATION, []string{rmaId})
+	if err != nil {
+		return rma, err
+	}
+	rmaResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR, rmaKey)
+	if err != nil {
+		return rma, err
+	}
+	if rmaResponse == nil {
+		return rma, fmt.Errorf("return authorization %s not found", rmaId)
+	}
+	err = json.Unmarshal(rmaResponse, &rma)
+	return rma, err
+}
+
+/*
+	Method: commitReturnAuthorization
+	Stores the RMA for customer and distributor and, once routed, the copy seen by the supplying manufacturer
+*/
+func commitReturnAuthorization(stub shim.ChaincodeStubInterface, rma ReturnAuthorization) ([]byte, error) {
+	rmaKey, err := stub.CreateCompositeKey(DOC_TYPE_RETURN_AUTHORIZATION, []string{rma.RmaId})
+	if err != nil {
+		return nil, err
+	}
+	rmaBytes, err := json.Marshal(rma)
+	if err != nil {
+		return nil, err
+	}
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR, rmaKey, rmaBytes)
+	if err != nil {
+		return nil, err
+	}
+	privateCollection := manufacturerCollection(rma.Manufacturer)
+	if privateCollection != "" && rma.Status != STATUS_RETURN_REQUESTED {
+		err = stub.PutPrivateData(privateCollection, rmaKey, rmaBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rmaBytes, nil
+}
+
+/*
+	Method: setReturnAuthorizationEvent
+	Emits an event for an RMA step
+*/
+func setReturnAuthorizationEvent(stub shim.ChaincodeStubInterface, eventType string, description string, rma ReturnAuthorization, custodian string) {
+	lineItems := make([]LineItem, 0)
+	for _, returnLine := range rma.ReturnLines {
+		lineItems = append(lineItems, LineItem{PoNumber: rma.PoNumber, LineNumber: returnLine.LineNumber, ItemKey: returnLine.ItemKey, MaterialId: returnLine.MaterialId, Quantity: returnLine.Quantity})
+	}
+	timeStamp := int64(0)
+	if len(rma.ProgressStatus) > 0 {
+		timeStamp = rma.ProgressStatus[len(rma.ProgressStatus)-1].TimeStamp
+	}
+	var event = CustomEvent{Type: eventType, Description: description, Status: rma.Status, Id: rma.RmaId, PoNumber: rma.PoNumber, Custodian: custodian, LineItems: lineItems, TimeStamp: timeStamp}
+	eventBytes, err := json.Marshal(&event)
+	if err != nil {
+		fmt.Println("unable to marshal event ", err)
+	}
+	err = stub.SetEvent(event.Type, eventBytes)
+	if err != nil {
+		fmt.Println("Could not set event for "+description+" ", err)
+	} else {
+		logger.Infof("Event set - type: %s description: %s", event.Type, event.Description)
+	}
+}
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_SHIPMENT_TENDER = "shipmentTender"
+)
+
+// tenders of each shipper go to their own collection with the carrier, so a manufacturer
+// never sees the loads the distributor or the other manufacturer tendered
+var carrierShipperCollections = map[string]map[string]string{
+	"org5msp": {"org2msp": PRIVATE_COLLECTION_CARRIER1, "org3msp": PRIVATE_COLLECTION_CARRIER1_MFR1, "org4msp": PRIVATE_COLLECTION_CARRIER1_MFR2},
+	"org9msp": {"org2msp": PRIVATE_COLLECTION_CARRIER2, "org3msp": PRIVATE_COLLECTION_CARRIER2_MFR1, "org4msp": PRIVATE_COLLECTION_CARRIER2_MFR2},
+}
+
+/*
+	Method: carrierCollection
+	Returns the private collection a shipper shares with a carrier and the name of the carrier.
+	The shipper is the organization name a shipping request is requested by.
+*/
+func carrierCollection(carrierMspId string, shipper string) (string, string) {
+	collections, found := carrierShipperCollections[str.ToLower(carrierMspId)]
+	if !found {
+		return "", ""
+	}
+	return collections[shipperMspId(shipper)], organizationMap[str.ToLower(carrierMspId)]
+}
+
+/*
+	Method: carrierCollections
+	Returns every collection a carrier receives tenders in, one per shipper
+*/
+func carrierCollections(carrierMspId string) []string {
+	collections := make([]string, 0)
+	shipperCollections, found := carrierShipperCollections[str.ToLower(carrierMspId)]
+	if !found {
+		return collections
+	}
+	for _, shipper := range []string{"org2msp", "org3msp", "org4msp"} {
+		collections = append(collections, shipperCollections[shipper])
+	}
+	return collections
+}
+
+/*
+	Method: isCarrierCollectionMember
+	Tells whether an organization can read and write the tenders a shipper shares with a carrier
+*/
+func isCarrierCollectionMember(mspId string, carrierMspId string, shipper string) bool {
+	mspId = str.ToLower(mspId)
+	return mspId == "org2msp" || mspId == str.ToLower(carrierMspId) || mspId == shipperMspId(shipper)
+}
+
+/*
+	The distributor or the manufacturer that requested the shipment tenders and awards it
+*/
+func isShipper(mspId string, shippingRequest ShippingRequest) bool {
+	mspId = str.ToLower(mspId)
+	return mspId == "org2msp" || mspId == shipperMspId(shippingRequest.RequestedBy)
+}
+
+/*
+	Shipping requests started before the manufacturers shipped on their own carry no requester
+	and were shipped by the distributor
+*/
+func shipperMspId(shipper string) string {
+	for _, mspId := range []string{"org3msp", "org4msp"} {
+		if organizationMap[mspId] == shipper {
+			return mspId
+		}
+	}
+	return "org2msp"
+}
+
+/*
+	Method: tenderShipment
+	Executed by the shipper to offer an open shipping request to one or more carriers.
+	Each carrier gets its own copy of the request in the collection it shares with the shipper and
+	never sees the other tenders.
+*/
+func (s *SmartContract) tenderShipment(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. shippingRequestNumber 2. carriers 3. timeStamp")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[0] + " Expecting an int64 number.")
+	}
+	carriers := []string{}
+	err = json.Unmarshal([]byte(args[1]), &carriers)
+	if err != nil || len(carriers) == 0 {
+		return shim.Error("Unable to parse carriers provided - " + args[1] + " Expecting a list of carrier MSP ids.")
+	}
+	timeStamp, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[2] + " Expecting a number.")
+	}
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Shipping Request not found - " + args[0])
+	}
+	if shippingRequest.AwardedTo != "" {
+		return shim.Error("Shipping Request " + args[0] + " is already awarded to " + organizationMap[shippingRequest.AwardedTo])
+	}
+	if shippingRequest.Status != STATUS_OPEN {
+		return shim.Error("Shipping Request " + args[0] + " is already " + shippingRequest.Status)
+	}
+	if !isShipper(currentMspId, shippingRequest) {
+		return shim.Error("Shipping Request " + args[0] + " is shipped by " + shippingRequest.RequestedBy)
+	}
+	// material under an open recall cannot be offered for pickup
+	if err := checkRecallHolds(stub, shippingRequest.PoId, shippingLineNumbers(shippingRequest)); err != nil {
+		return shim.Error(err.Error())
+	}
+	tenderedBy := organizationMap[currentMspId]
+	progressStatus := ItemStatus{Owner: tenderedBy, Status: STATUS_TENDERED, TimeStamp: timeStamp}
+	carrierNames := make([]string, 0)
+	for _, carrier := range carriers {
+		carrier = str.ToLower(carrier)
+		collection, carrierName := carrierCollection(carrier, shippingRequest.RequestedBy)
+		if collection == "" {
+			return shim.Error("Unknown carrier - " + carrier)
+		}
+		existing, found, err := getShipmentTender(stub, collection, shippingRequest.PoId, shippingRequestNumber)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if found && existing.Status != STATUS_DECLINED && existing.Status != STATUS_NOT_AWARDED {
+			return shim.Error("Shipping Request " + args[0] + " is already tendered to " + carrierName)
+		}
+		tender := ShipmentTender{
+			ObjectType:            DOC_TYPE_SHIPMENT_TENDER,
+			ShippingRequestNumber: shippingRequestNumber,
+			PoId:                  shippingRequest.PoId,
+			PoNumber:              shippingRequest.PoNumber,
+			Carrier:               carrier,
+			TenderedBy:            tenderedBy,
+			Status:                STATUS_TENDERED,
+			ShippingRequest:       tenderedCopy(shippingRequest),
+			TenderedTimeStamp:     timeStamp,
+			ProgressStatus:        []ItemStatus{progressStatus},
+		}
+		if err := commitShipmentTender(stub, collection, tender); err != nil {
+			return shim.Error(err.Error())
+		}
+		if !containsFold(shippingRequest.TenderedTo, carrier) {
+			shippingRequest.TenderedTo = append(shippingRequest.TenderedTo, carrier)
+		}
+		carrierNames = append(carrierNames, carrierName)
+	}
+	if err := commitShippingRequest(stub, shippingRequest); err != nil {
+		return shim.Error(err.Error())
+	}
+	setShipmentTenderEvent(stub, "shipmenttendered", "Shipment Tendered", STATUS_TENDERED, shippingRequest, carrierNames, timeStamp)
+	shippingRequestBytes, _ := json.Marshal(shippingRequest)
+	return shim.Success(shippingRequestBytes)
+}
+
+/*
+	Method: respondToTender
+	Executed by a carrier to accept, decline or quote a load tendered to it.
+	The answer can change until the shipper awards the load.
+*/
+func (s *SmartContract) respondToTender(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. poId 2. shippingRequestNumber 3. response")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[1] + " Expecting an int64 number.")
+	}
+	response := TenderResponse{}
+	err = json.Unmarshal([]byte(args[2]), &response)
+	if err != nil {
+		return shim.Error("Unable to parse tender response provided - " + args[2])
+	}
+	carrierName := organizationMap[currentMspId]
+	tender, collection, found, err := findCarrierTender(stub, currentMspId, args[0], shippingRequestNumber)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Shipping Request " + args[1] + " is not tendered to " + carrierName)
+	}
+	switch tender.Status {
+	case STATUS_TENDERED, STATUS_ACCEPTED, STATUS_QUOTED:
+	default:
+		return shim.Error("Tender for Shipping Request " + args[1] + " is already " + tender.Status)
+	}
+	switch response.Status {
+	case STATUS_ACCEPTED:
+		tender.Quote = response.Quote
+		tender.DeclineReason = ""
+	case STATUS_QUOTED:
+		if response.Quote == nil || response.Quote.Amount <= 0 {
+			return shim.Error("A quote with an amount is required.")
+		}
+		if response.Quote.Currency == "" {
+			response.Quote.Currency = DEFAULT_CURRENCY
+		}
+		tender.Quote = response.Quote
+		tender.DeclineReason = ""
+	case STATUS_DECLINED:
+		tender.Quote = nil
+		tender.DeclineReason = response.Reason
+	default:
+		return shim.Error("Unexpected response status " + response.Status + ", expecting accepted, declined or quoted")
+	}
+	tender.Status = response.Status
+	tender.RespondedTimeStamp = response.TimeStamp
+	tender.ProgressStatus = append(tender.ProgressStatus, ItemStatus{Owner: carrierName, Status: response.Status, TimeStamp: response.TimeStamp})
+	if err := commitShipmentTender(stub, collection, tender); err != nil {
+		return shim.Error(err.Error())
+	}
+	setShipmentTenderEvent(stub, "shipmenttenderresponse", "Shipment Tender "+str.Title(response.Status), response.Status, tender.ShippingRequest, []string{carrierName}, response.TimeStamp)
+	tenderBytes, _ := json.Marshal(tender)
+	return shim.Success(tenderBytes)
+}
+
+/*
+	Method: awardShipment
+	Executed by the shipper to give a load to one of the carriers that accepted or quoted it.
+	The other open tenders are closed as not awarded.
+*/
+func (s *SmartContract) awardShipment(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. shippingRequestNumber 2. carrier 3. timeStamp")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[0] + " Expecting an int64 number.")
+	}
+	carrier := str.ToLower(args[1])
+	timeStamp, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[2] + " Expecting a number.")
+	}
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Shipping Request not found - " + args[0])
+	}
+	if shippingRequest.AwardedTo != "" {
+		return shim.Error("Shipping Request " + args[0] + " is already awarded to " + organizationMap[shippingRequest.AwardedTo])
+	}
+	if !containsFold(shippingRequest.TenderedTo, carrier) {
+		return shim.Error("Shipping Request " + args[0] + " is not tendered to " + args[1])
+	}
+	if !isShipper(currentMspId, shippingRequest) {
+		return shim.Error("Shipping Request " + args[0] + " is shipped by " + shippingRequest.RequestedBy)
+	}
+	if shippingRequest.LoadId != "" {
+		if err := checkLoadCarrier(stub, shippingRequest.LoadId, carrier); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	if err := checkRecallHolds(stub, shippingRequest.PoId, shippingLineNumbers(shippingRequest)); err != nil {
+		return shim.Error(err.Error())
+	}
+	awardedBy := organizationMap[currentMspId]
+	awarded := ShipmentTender{}
+	for _, tenderedTo := range shippingRequest.TenderedTo {
+		collection, carrierName := carrierCollection(tenderedTo, shippingRequest.RequestedBy)
+		tender, found, err := getShipmentTender(stub, collection, shippingRequest.PoId, shippingRequestNumber)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if !found {
+			continue
+		}
+		if tenderedTo == carrier {
+			if tender.Status != STATUS_ACCEPTED && tender.Status != STATUS_QUOTED {
+				return shim.Error(carrierName + " has not accepted or quoted Shipping Request " + args[0])
+			}
+			awarded = tender
+			continue
+		}
+		if tender.Status == STATUS_DECLINED || tender.Status == STATUS_NOT_AWARDED {
+			continue
+		}
+		tender.Status = STATUS_NOT_AWARDED
+		tender.ProgressStatus = append(tender.ProgressStatus, ItemStatus{Owner: awardedBy, Status: STATUS_NOT_AWARDED, TimeStamp: timeStamp})
+		if err := commitShipmentTender(stub, collection, tender); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	if awarded.Carrier == "" {
+		return shim.Error("Tender for Shipping Request " + args[0] + " not found for " + args[1])
+	}
+	shippingRequest.AwardedTo = carrier
+	if err := commitShippingRequest(stub, shippingRequest); err != nil {
+		return shim.Error(err.Error())
+	}
+	collection, carrierName := carrierCollection(carrier, shippingRequest.RequestedBy)
+	awarded.Status = STATUS_AWARDED
+	awarded.AwardedTimeStamp = timeStamp
+	awarded.ShippingRequest = tenderedCopy(shippingRequest)
+	awarded.ProgressStatus = append(awarded.ProgressStatus, ItemStatus{Owner: awardedBy, Status: STATUS_AWARDED, TimeStamp: timeStamp})
+	if err := commitShipmentTender(stub, collection, awarded); err != nil {
+		return shim.Error(err.Error())
+	}
+	setShipmentTenderEvent(stub, "shipmentawarded", "Shipment Awarded", STATUS_AWARDED, shippingRequest, []string{carrierName}, timeStamp)
+	awardedBytes, _ := json.Marshal(awarded)
+	return shim.Success(awardedBytes)
+}
+
+/*
+	Method: queryShipmentTenders
+	Returns every tender of a shipping request with the carriers' answers, for the shipper to compare
+*/
+func (s *SmartContract) queryShipmentTenders(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. shippingRequestNumber")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[0] + " Expecting an int64 number.")
+	}
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if !found {
+		return shim.Error("Shipping Request not found - " + args[0])
+	}
+	tenders := make([]ShipmentTender, 0)
+	for _, tenderedTo := range shippingRequest.TenderedTo {
+		collection, _ := carrierCollection(tenderedTo, shippingRequest.RequestedBy)
+		tender, found, err := getShipmentTender(stub, collection, shippingRequest.PoId, shippingRequestNumber)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if found {
+			tenders = append(tenders, tender)
+		}
+	}
+	tenderBytes, _ := json.Marshal(tenders)
+	return shim.Success(tenderBytes)
+}
+
+/*
+	Method: carrierLoads
+	Returns the loads tendered or awarded to the calling carrier, grouped by PO.
+	Declined and lost tenders are left out.
+*/
+func carrierLoads(stub shim.ChaincodeStubInterface) ([]ShippingRequestsResults, error) {
+	collections := carrierCollections(currentMspId)
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("organization %s is not a carrier", currentMspId)
+	}
+	shippingRequestsResults := make([]ShippingRequestsResults, 0)
+	indexByPoId := make(map[string]int)
+	for _, collection := range collections {
+		tenders, err := getShipmentTendersForPo(stub, collection, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, tender := range tenders {
+			switch tender.Status {
+			case STATUS_TENDERED, STATUS_ACCEPTED, STATUS_QUOTED, STATUS_AWARDED:
+			default:
+				continue
+			}
+			index, found := indexByPoId[tender.PoId]
+			if !found {
+				srr := ShippingRequestsResults{PoId: tender.PoId, PoNumber: tender.PoNumber, LineItems: make([]ShippingLineItem, 0)}
+				if value, err := stub.GetState(tender.PoId); err == nil && value != nil {
+					po := PurchaseOrder{}
+					json.Unmarshal(value, &po)
+					srr.PoStatus = po.PoStatus
+					srr.Owner = po.Owner
+					srr.ExpectedDeliveryDate = po.ExpectedDeliveryDate
+				}
+				shippingRequestsResults = append(shippingRequestsResults, srr)
+				index = len(shippingRequestsResults) - 1
+				indexByPoId[tender.PoId] = index
+			}
+			shippingRequestsResults[index].LineItems = append(shippingRequestsResults[index].LineItems, tender.ShippingRequest.LineItems...)
+			shippingRequestsResults[index].Tenders = append(shippingRequestsResults[index].Tenders, tender)
+		}
+	}
+	return shippingRequestsResults, nil
+}
+
+/*
+	Method: syncShippingRequestsFromTenders
+	Brings the shipper's copy of the awarded shipping requests of a PO up to date with
+	the pickups the carriers recorded on their tenders
+*/
+func syncShippingRequestsFromTenders(stub shim.ChaincodeStubInterface, poId string) {
+	shippingRequests, err := getShippingRequestsForPo(stub, poId)
+	if err != nil {
+		logger.Info("unable to find shipping requests for: " + poId + " error: " + err.Error())
+		return
+	}
+	for _, shippingRequest := range shippingRequests {
+		if shippingRequest.AwardedTo == "" {
+			continue
+		}
+		collection, _ := carrierCollection(shippingRequest.AwardedTo, shippingRequest.RequestedBy)
+		tender, found, err := getShipmentTender(stub, collection, poId, shippingRequest.ShippingRequestNumber)
+		if err != nil || !found {
+			continue
+		}
+		shippingRequest.LineItems = tender.ShippingRequest.LineItems
+		shippingRequest.TimeShipped = tender.ShippingRequest.TimeShipped
+		refreshShippingRequestStatus(&shippingRequest)
+		if err := commitShippingRequest(stub, shippingRequest); err != nil {
+			logger.Error("Unable to update shipping request: ", err)
+		}
+	}
+}
+
+/*
+	Method: syncAwardedTender
+	Passes delivery updates of a shipping request on to the carrier it was awarded to.
+	Only members of the carrier's collection can do it, the distributor syncs the others
+	when it records the delivery.
+*/
+func syncAwardedTender(stub shim.ChaincodeStubInterface, shippingRequest ShippingRequest) error {
+	if shippingRequest.AwardedTo == "" {
+		return nil
+	}
+	if !isCarrierCollectionMember(currentMspId, shippingRequest.AwardedTo, shippingRequest.RequestedBy) {
+		collection, _ := carrierCollection(shippingRequest.AwardedTo, shippingRequest.RequestedBy)
+		return fmt.Errorf("organization %s is not a member of %s, unable to update the tender of shipping request %d", currentMspId, collection, shippingRequest.ShippingRequestNumber)
+	}
+	return commitTenderedShippingRequest(stub, shippingRequest.AwardedTo, shippingRequest)
+}
+
+/*
+	Method: syncCarrierTenders
+	Executed by the distributor to bring the tenders awarded for a PO up to date with its
+	shipping requests, for updates recorded by organizations outside the carriers' collections
+*/
+func (s *SmartContract) syncCarrierTenders(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. poId")
+	}
+	shippingRequests, err := syncAwardedTendersForPo(stub, args[0])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	shippingRequestBytes, _ := json.Marshal(shippingRequests)
+	return shim.Success(shippingRequestBytes)
+}
+
+func syncAwardedTendersForPo(stub shim.ChaincodeStubInterface, poId string) ([]ShippingRequest, error) {
+	shippingRequests, err := getShippingRequestsForPo(stub, poId)
+	if err != nil {
+		return nil, err
+	}
+	synced := make([]ShippingRequest, 0)
+	for _, shippingRequest := range shippingRequests {
+		if shippingRequest.AwardedTo == "" {
+			continue
+		}
+		if err := syncAwardedTender(stub, shippingRequest); err != nil {
+			return nil, err
+		}
+		synced = append(synced, shippingRequest)
+	}
+	return synced, nil
+}
+
+/*
+	Method: shipmentCarrier
+	Returns the name of the carrier moving a shipment. Shipments that left before loads
+	were tendered were all moved by the logistics org.
+*/
+func shipmentCarrier(stub shim.ChaincodeStubInterface, shipmentId string) string {
+	shippingRequestNumber, err := strconv.ParseInt(shipmentId, 10, 64)
+	if err != nil {
+		return ""
+	}
+	shippingRequest, found, err := getShippingRequest(stub, shippingRequestNumber)
+	if err != nil || !found {
+		return ""
+	}
+	if shippingRequest.AwardedTo != "" {
+		return organizationMap[shippingRequest.AwardedTo]
+	}
+	if shippingRequest.Status != STATUS_OPEN {
+		return organizationMap["org5msp"]
+	}
+	return ""
+}
+
+/*
+	Method: commitTenderedShippingRequest
+	Replaces the copy of a shipping request held in a carrier's tender
+*/
+func commitTenderedShippingRequest(stub shim.ChaincodeStubInterface, carrierMspId string, shippingRequest ShippingRequest) error {
+	collection, carrierName := carrierCollection(carrierMspId, shippingRequest.RequestedBy)
+	tender, found, err := getShipmentTender(stub, collection, shippingRequest.PoId, shippingRequest.ShippingRequestNumber)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("shipping request %d is not tendered to %s", shippingRequest.ShippingRequestNumber, carrierName)
+	}
+	tender.ShippingRequest = tenderedCopy(shippingRequest)
+	return commitShipmentTender(stub, collection, tender)
+}
+
+/*
+	A carrier's copy does not name the other carriers the load was offered to
+*/
+func tenderedCopy(shippingRequest ShippingRequest) ShippingRequest {
+	shippingRequest.TenderedTo = nil
+	return shippingRequest
+}
+
+func shippingLineNumbers(shippingRequest ShippingRequest) []int {
+	lineNumbers := make([]int, 0)
+	for _, shipLineItem := range shippingRequest.LineItems {
+		lineNumbers = append(lineNumbers, shipLineItem.LineNumber)
+	}
+	return lineNumbers
+}
+
+func commitShipmentTender(stub shim.ChaincodeStubInterface, collection string, tender ShipmentTender) error {
+	tenderKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPMENT_TENDER, []string{tender.PoId, strconv.FormatInt(tender.ShippingRequestNumber, 10)})
+	if err != nil {
+		return err
+	}
+	tenderBytes, err := json.Marshal(tender)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(collection, tenderKey, tenderBytes)
+}
+
+func getShipmentTender(stub shim.ChaincodeStubInterface, collection string, poId string, shippingRequestNumber int64) (ShipmentTender, bool, error) {
+	tender := ShipmentTender{}
+	if collection == "" {
+		return tender, false, nil
+	}
+	tenderKey, err := stub.CreateCompositeKey(DOC_TYPE_SHIPMENT_TENDER, []string{poId, strconv.FormatInt(shippingRequestNumber, 10)})
+	if err != nil {
+		return tender, false, err
+	}
+	tenderBytes, err := stub.GetPrivateData(collection, tenderKey)
+	if err != nil || tenderBytes == nil {
+		return tender, false, err
+	}
+	json.Unmarshal(tenderBytes, &tender)
+	return tender, true, nil
+}
+
+/*
+	A carrier answering a tender does not know who shipped it, so every collection of the carrier is looked at
+*/
+func findCarrierTender(stub shim.ChaincodeStubInterface, carrierMspId string, poId string, shippingRequestNumber int64) (ShipmentTender, string, bool, error) {
+	for _, collection := range carrierCollections(carrierMspId) {
+		tender, found, err := getShipmentTender(stub, collection, poId, shippingRequestNumber)
+		if err != nil {
+			return tender, "", false, err
+		}
+		if found {
+			return tender, collection, true, nil
+		}
+	}
+	return ShipmentTender{}, "", false, nil
+}
+
+func getShipmentTendersForPo(stub shim.ChaincodeStubInterface, collection string, poId string) ([]ShipmentTender, error) {
+	tenders := make([]ShipmentTender, 0)
+	if collection == "" {
+		return tenders, nil
+	}
+	keys := []string{poId}
+	if poId == "" {
+		keys = []string{}
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(collection, DOC_TYPE_SHIPMENT_TENDER, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		tender := ShipmentTender{}
+		json.Unmarshal(queryResponse.Value, &tender)
+		tenders = append(tenders, tender)
+	}
+	return tenders, nil
+}
+
+func setShipmentTenderEvent(stub shim.ChaincodeStubInterface, eventType string, description string, status string, shippingRequest ShippingRequest, carriers []string, timeStamp int64) {
+	var event = ShipmentTenderEvent{Type: eventType, Description: description, Status: status, ShippingRequestNumber: shippingRequest.ShippingRequestNumber, PoId: shippingRequest.PoId, PoNumber: shippingRequest.PoNumber, Carriers: carriers, TimeStamp: timeStamp}
+	eventBytes, err := json.Marshal(&event)
+	if err != nil {
+		fmt.Println("unable to marshal event ", err)
+	}
+	err = stub.SetEvent(event.Type, eventBytes)
+	if err != nil {
+		fmt.Println("Could not set event for "+description+" ", err)
+	} else {
+		logger.Infof("Event set - type: %s description: %s", event.Type, event.Description)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_SERIALIZED_UNIT = "serializedUnit"
+)
+
+/*
+	Method: registerSerializedUnits
+	Executed when the manufacturer, or the distributor for inventory, identifies the units of an order request.
+	Units are shared in the general progress collection so the customer can follow each joint.
+*/
+func (s *SmartContract) registerSerializedUnits(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. poId 2. itemKey 3. units 4. progress status")
+	}
+	poId := args[0]
+	itemKey := args[1]
+	units := []SerializedUnit{}
+	err := json.Unmarshal([]byte(args[2]), &units)
+	if err != nil {
+		return shim.Error("Unable to parse serialized unit data provided - " + args[2])
+	}
+	progressStatus := ItemStatus{}
+	err = json.Unmarshal([]byte(args[3]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[3])
+	}
+	orderedLine, fulfilledBy, err := getOrderRequestLine(stub, poId, itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	existing, err := getSerializedUnits(stub, poId, itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	registered := 0
+	for _, unit := range existing {
+		if unit.FulfilledBy == fulfilledBy {
+			registered += 1
+		}
+	}
+	// units are counted pieces, so the ordered quantity only caps them when it converts to a count
+	ordered, baseUnit, _ := baseQuantity(stub, orderedLine.MaterialId, float64(orderedLine.Quantity), orderedLine.UnitOfMeasure)
+	if isCountUnit(baseUnit) && float64(registered+len(units)) > math.Ceil(ordered) {
+		return shim.Error(fmt.Sprintf("Cannot register %d units, %d of %v %s already registered for %s", len(units), registered, math.Ceil(ordered), baseUnit, itemKey))
+	}
+	seen := make(map[string]bool)
+	for i, unit := range units {
+		if unit.SerialNumber == "" || unit.HeatNumber == "" {
+			return shim.Error("serialNumber and heatNumber are required for every unit.")
+		}
+		if seen[unit.SerialNumber] {
+			return shim.Error("Duplicate serial number - " + unit.SerialNumber)
+		}
+		seen[unit.SerialNumber] = true
+		if _, found := getSerializedUnit(stub, poId, itemKey, unit.SerialNumber); found {
+			return shim.Error("Serial number already registered - " + unit.SerialNumber)
+		}
+		if unit.TrackingId != "" && !materialCertificateExists(stub, unit.TrackingId) {
+			return shim.Error("Material certificate not found - " + unit.TrackingId)
+		}
+		units[i].ObjectType = DOC_TYPE_SERIALIZED_UNIT
+		units[i].PoId = poId
+		units[i].PoNumber = orderedLine.PoNumber
+		units[i].LineNumber = orderedLine.LineNumber
+		units[i].ItemKey = itemKey
+		units[i].MaterialId = orderedLine.MaterialId
+		units[i].FulfilledBy = fulfilledBy
+		units[i].Status = STATUS_OPEN
+		units[i].ProgressStatus = []ItemStatus{progressStatus}
+		err = commitSerializedUnit(stub, units[i])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	// link the material certificates the units were made under to the order request
+	linked := make(map[string]bool)
+	for _, unit := range units {
+		if unit.TrackingId == "" || linked[unit.TrackingId+"|"+unit.HeatNumber] {
+			continue
+		}
+		linked[unit.TrackingId+"|"+unit.HeatNumber] = true
+		recordMtrGenealogy(stub, unit.HeatNumber, unit.TrackingId, poId, itemKey, fulfilledBy, progressStatus.TimeStamp)
+	}
+	unitBytes, _ := json.Marshal(units)
+	return shim.Success(unitBytes)
+}
+
+/*
+	Method: installSerializedUnits
+	Executed when the customer installs received units in the field
+*/
+func (s *SmartContract) installSerializedUnits(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 5. 1. poId 2. itemKey 3. serial numbers 4. installation location 5. progress status")
+	}
+	serialNumbers := []string{}
+	err := json.Unmarshal([]byte(args[2]), &serialNumbers)
+	if err != nil {
+		return shim.Error("Unable to parse serial numbers provided - " + args[2])
+	}
+	progressStatus := ItemStatus{}
+	err = json.Unmarshal([]byte(args[4]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[4])
+	}
+	progressStatus.Status = STATUS_INSTALLED
+	units := make([]SerializedUnit, 0)
+	for _, serialNumber := range serialNumbers {
+		unit, found := getSerializedUnit(stub, args[0], args[1], serialNumber)
+		if !found {
+			return shim.Error("Serialized unit not found - " + serialNumber)
+		}
+		if unit.Status != STATUS_RECEIVED {
+			return shim.Error(fmt.Sprintf("Serialized unit %s is %s, only received units can be installed", serialNumber, unit.Status))
+		}
+		unit.Status = STATUS_INSTALLED
+		unit.InstallationLocation = args[3]
+		unit.ProgressStatus = append(unit.ProgressStatus, progressStatus)
+		err = commitSerializedUnit(stub, unit)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		units = append(units, unit)
+	}
+	unitBytes, _ := json.Marshal(units)
+	return shim.Success(unitBytes)
+}
+
+/*
+	Method: querySerializedUnits
+	Returns the serialized units of a purchase order, optionally for a single line item
+*/
+func (s *SmartContract) querySerializedUnits(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) < 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. poId 2. itemKey (optional)")
+	}
+	itemKey := ""
+	if len(args) > 1 {
+		itemKey = args[1]
+	}
+	units, err := getSerializedUnits(stub, args[0], itemKey)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	unitBytes, _ := json.Marshal(units)
+	return shim.Success(unitBytes)
+}
+
+/*
+	Method: updateSerializedUnitStatus
+	Moves the units of a line item fulfilled by an organization from one status to the next,
+	an empty fulfilledBy updates units of every supplier
+*/
+func updateSerializedUnitStatus(stub shim.ChaincodeStubInterface, poId string, itemKey string, fulfilledBy string, fromStatus string, progressStatus ItemStatus, update func(unit *SerializedUnit)) {
+	units, err := getSerializedUnits(stub, poId, itemKey)
+	if err != nil {
+		logger.Infof("Unable to get serialized units for: %s error: %s", itemKey, err.Error())
+		return
+	}
+	for _, unit := range units {
+		if unit.Status != fromStatus || (fulfilledBy != "" && unit.FulfilledBy != fulfilledBy) {
+			continue
+		}
+		unit.Status = progressStatus.Status
+		unit.ProgressStatus = append(unit.ProgressStatus, progressStatus)
+		if update != nil {
+			update(&unit)
+		}
+		if err := commitSerializedUnit(stub, unit); err != nil {
+			logger.Infof("Unable to update serialized unit: %s error: %s", unit.SerialNumber, err.Error())
+		}
+	}
+}
+
+/*
+	Method: shipSerializedUnits
+	Marks the registered units of a line item as shipped under a shipping request
+*/
+func shipSerializedUnits(stub shim.ChaincodeStubInterface, poId string, itemKey string, fulfilledBy string, shippingRequestNumber int64, iotTrackingCode string, progressStatus ItemStatus) {
+	progressStatus.Status = STATUS_SHIPPED
+	updateSerializedUnitStatus(stub, poId, itemKey, fulfilledBy, STATUS_OPEN, progressStatus, func(unit *SerializedUnit) {
+		unit.ShippingRequestNumber = shippingRequestNumber
+		unit.IotTrackingCode = iotTrackingCode
+	})
+}
+
+/*
+	Method: getOrderRequestLine
+	Returns the line ordered from the calling organization and the name it fulfills under
+*/
+func getOrderRequestLine(stub shim.ChaincodeStubInterface, poId string, itemKey string) (LineItem, string, error) {
+	switch currentMspId {
+	case "org3msp", "org4msp":
+		manufacturer := organizationMap[currentMspId]
+		privateCollection := manufacturerCollection(manufacturer)
+		poPrivateDataResponse, err := stub.GetPrivateData(privateCollection, poId)
+		if err != nil || poPrivateDataResponse == nil {
+			return LineItem{}, "", fmt.Errorf("no records found for PO: %s", poId)
+		}
+		itemPrivateData := LineItemCDPrivateDetails{}
+		json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
+		for _, pricingInfo := range itemPrivateData.LineItems {
+			if pricingInfo.ItemKey == itemKey {
+				return LineItem{PoNumber: pricingInfo.PoNumber, LineNumber: pricingInfo.LineNumber, MaterialId: pricingInfo.MaterialId, MaterialGroup: pricingInfo.MaterialGroup, Quantity: pricingInfo.Quantity, UnitOfMeasure: pricingInfo.UnitOfMeasure}, manufacturer, nil
+			}
+		}
+	case "org2msp":
+		distributor := organizationMap[currentMspId]
+		poPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, poId)
+		if err != nil || poPrivateDataResponse == nil {
+			return LineItem{}, "", fmt.Errorf("no records found for PO: %s", poId)
+		}
+		itemPrivateData := LineItemPrivateDetails{}
+		json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
+		for _, lineItem := range itemPrivateData.LineItems {
+			if lineItem.ItemKey != itemKey {
+				continue
+			}
+			for _, orderRequest := range lineItem.OrderRequests {
+				if orderRequest.FulfilledBy == distributor {
+					lineItem.Quantity = orderRequest.Quantity
+					return lineItem, distributor, nil
+				}
+			}
+		}
+	}
+	return LineItem{}, "", fmt.Errorf("line item %s is not supplied by %s", itemKey, currentMspId)
+}
+
+/*
+	Method: materialCertificateExists
+	Checks the material certificate collections readable by the caller for a tracking id
+*/
+func materialCertificateExists(stub shim.ChaincodeStubInterface, trackingId string) bool {
+	_, _, found := getMaterialCertificate(stub, trackingId)
+	return found
+}
+
+func getSerializedUnit(stub shim.ChaincodeStubInterface, poId string, itemKey string, serialNumber string) (SerializedUnit, bool) {
+	unit := SerializedUnit{}
+	unitKey, err := stub.CreateCompositeKey(DOC_TYPE_SERIALIZED_UNIT, []string{poId, itemKey, serialNumber})
+	if err != nil {
+		return unit, false
+	}
+	unitBytes, err := stub.GetPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, unitKey)
+	if err != nil || unitBytes == nil {
+		return unit, false
+	}
+	json.Unmarshal(unitBytes, &unit)
+	return unit, true
+}
+
+func getSerializedUnits(stub shim.ChaincodeStubInterface, poId string, itemKey string) ([]SerializedUnit, error) {
+	keys := []string{poId}
+	if itemKey != "" {
+		keys = append(keys, itemKey)
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_GENERAL_PROGRESS, DOC_TYPE_SERIALIZED_UNIT, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	units := make([]SerializedUnit, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		unit := SerializedUnit{}
+		json.Unmarshal(queryResponse.Value, &unit)
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].ItemKey+units[i].SerialNumber < units[j].ItemKey+units[j].SerialNumber
+	})
+	return units, nil
+}
+
+func commitSerializedUnit(stub shim.ChaincodeStubInterface, unit SerializedUnit) error {
+	unitKey, err := stub.CreateCompositeKey(DOC_TYPE_SERIALIZED_UNIT, []string{unit.PoId, unit.ItemKey, unit.SerialNumber})
+	if err != nil {
+		return err
+	}
+	unitBytes, err := json.Marshal(unit)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, unitKey, unitBytes)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+/*
+	Method: convertUnits
+	Converts a quantity of a material between two units of measure using the catalog conversion factors
+*/
+func (s *SmartContract) convertUnits(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. materialId 2. quantity 3. from unit of measure 4. to unit of measure")
+	}
+	quantity, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return shim.Error("Unable to parse quantity provided - " + args[1] + " Expecting a number.")
+	}
+	material, found, err := getMaterial(stub, args[0])
+	if err != nil || !found {
+		return shim.Error("Material not found in catalog - " + args[0])
+	}
+	converted, err := convertQuantity(material, quantity, args[2], args[3])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	result := UnitConversionResult{MaterialId: material.MaterialId, Quantity: quantity, UnitOfMeasure: args[2], ConvertedQuantity: converted, ConvertedUnitOfMeasure: args[3]}
+	resultBytes, _ := json.Marshal(result)
+	return shim.Success(resultBytes)
+}
+
+/*
+	Method: unitFactor
+	Returns the number of base units in one unit of measure of a material
+*/
+func unitFactor(material Material, unitOfMeasure string) (float64, error) {
+	if str.EqualFold(unitOfMeasure, material.BaseUnitOfMeasure) {
+		return 1, nil
+	}
+	for _, conversion := range material.Conversions {
+		if str.EqualFold(conversion.UnitOfMeasure, unitOfMeasure) && conversion.Factor > 0 {
+			return conversion.Factor, nil
+		}
+	}
+	return 0, fmt.Errorf("no conversion from %s to %s defined for material %s", unitOfMeasure, material.BaseUnitOfMeasure, material.MaterialId)
+}
+
+/*
+	Method: convertQuantity
+	Converts a quantity of a material from one unit of measure to another
+*/
+func convertQuantity(material Material, quantity float64, fromUnit string, toUnit string) (float64, error) {
+	fromFactor, err := unitFactor(material, fromUnit)
+	if err != nil {
+		return 0, err
+	}
+	toFactor, err := unitFactor(material, toUnit)
+	if err != nil {
+		return 0, err
+	}
+	return roundUnits(quantity * fromFactor / toFactor), nil
+}
+
+/*
+	Method: baseQuantity
+	Returns a quantity in the base unit of the material along with the base unit and the factor used.
+	Materials missing from the catalog are left in the unit they were given in.
+*/
+func baseQuantity(stub shim.ChaincodeStubInterface, materialId string, quantity float64, unitOfMeasure string) (float64, string, float64) {
+	material, found, err := getMaterial(stub, materialId)
+	if err != nil || !found {
+		return quantity, unitOfMeasure, 1
+	}
+	factor, err := unitFactor(material, unitOfMeasure)
+	if err != nil {
+		logger.Infof("baseQuantity: %s", err.Error())
+		return quantity, unitOfMeasure, 1
+	}
+	return roundUnits(quantity * factor), material.BaseUnitOfMeasure, factor
+}
+
+/*
+	Method: normalizePricing
+	Fills the base unit quantity and price of a pricing line
+*/
+func normalizePricing(stub shim.ChaincodeStubInterface, pricingInfo LineItemPricing) LineItemPricing {
+	quantity, unit, factor := baseQuantity(stub, pricingInfo.MaterialId, float64(pricingInfo.Quantity), pricingInfo.UnitOfMeasure)
+	return basePricing(pricingInfo, quantity, unit, factor)
+}
+
+/*
+	Method: basePricing
+	Sets the base unit quantity and price of a pricing line. The base unit price keeps full precision,
+	a price per joint divided down to a price per foot would lose fractions of a cent if rounded,
+	only extended amounts such as the subtotal are rounded.
+*/
+func basePricing(pricingInfo LineItemPricing, quantity float64, unitOfMeasure string, factor float64) LineItemPricing {
+	pricingInfo.BaseQuantity = quantity
+	pricingInfo.BaseUnitOfMeasure = unitOfMeasure
+	pricingInfo.BaseUnitPrice = pricingInfo.UnitPrice / factor
+	return pricingInfo
+}
+
+/*
+	Method: normalizeShippingLineItem
+	Fills the base unit quantity of a shipping line
+*/
+func normalizeShippingLineItem(stub shim.ChaincodeStubInterface, shipLineItem ShippingLineItem) ShippingLineItem {
+	shipLineItem.BaseQuantity, shipLineItem.BaseUnitOfMeasure, _ = baseQuantity(stub, shipLineItem.MaterialId, float64(shipLineItem.Quantity), shipLineItem.UnitOfMeasure)
+	return shipLineItem
+}
+
+func roundUnits(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
+
+/*
+	Method: isCountUnit
+	Whether a unit of measure counts individual pieces, e.g. each or joint
+*/
+func isCountUnit(unitOfMeasure string) bool {
+	switch str.ToLower(unitOfMeasure) {
+	case DEFAULT_UNIT_OF_MEASURE, "ea", "pc", "pcs", "piece", "pieces", "joint", "joints", "jt", "unit", "units":
+		return true
+	}
+	return false
+}
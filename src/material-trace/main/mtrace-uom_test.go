@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+var testPipe = Material{
+	MaterialId:        "P-12-STD",
+	BaseUnitOfMeasure: "ft",
+	Conversions: []UnitConversion{
+		{UnitOfMeasure: "joint", Factor: 40},
+		{UnitOfMeasure: "m", Factor: 3.28084},
+		{UnitOfMeasure: "broken", Factor: 0},
+	},
+}
+
+func TestConvertQuantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		from     string
+		to       string
+		want     float64
+		wantErr  bool
+	}{
+		{"base unit", 12, "ft", "ft", 12, false},
+		{"to base unit", 3, "joint", "ft", 120, false},
+		{"from base unit", 100, "ft", "joint", 2.5, false},
+		{"unit names ignore case", 2, "JOINT", "FT", 80, false},
+		{"between two conversions", 1, "joint", "m", 12.192, false},
+		{"rounded to thousandths", 1, "ft", "m", 0.305, false},
+		{"unknown unit", 1, "ton", "ft", 0, true},
+		{"zero factor is not a conversion", 1, "broken", "ft", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertQuantity(testPipe, tt.quantity, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("convertQuantity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBasePricing(t *testing.T) {
+	tests := []struct {
+		name      string
+		pricing   LineItemPricing
+		wantUnit  string
+		wantQty   float64
+		wantPrice float64
+	}{
+		{"base unit", LineItemPricing{Quantity: 10, UnitOfMeasure: "ft", UnitPrice: 4.25}, "ft", 10, 4.25},
+		{"price per joint to price per foot", LineItemPricing{Quantity: 3, UnitOfMeasure: "joint", UnitPrice: 123.45}, "ft", 120, 3.08625},
+		{"fractions of a cent are kept", LineItemPricing{Quantity: 7, UnitOfMeasure: "joint", UnitPrice: 0.5}, "ft", 280, 0.0125},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factor, err := unitFactor(testPipe, tt.pricing.UnitOfMeasure)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := basePricing(tt.pricing, roundUnits(float64(tt.pricing.Quantity)*factor), testPipe.BaseUnitOfMeasure, factor)
+			if got.BaseUnitOfMeasure != tt.wantUnit || got.BaseQuantity != tt.wantQty {
+				t.Errorf("basePricing() = %v %s, want %v %s", got.BaseQuantity, got.BaseUnitOfMeasure, tt.wantQty, tt.wantUnit)
+			}
+			if math.Abs(got.BaseUnitPrice-tt.wantPrice) > 1e-9 {
+				t.Errorf("basePricing() unit price = %v, want %v", got.BaseUnitPrice, tt.wantPrice)
+			}
+			extended := float64(tt.pricing.Quantity) * tt.pricing.UnitPrice
+			if math.Abs(got.BaseQuantity*got.BaseUnitPrice-extended) > 1e-9 {
+				t.Errorf("base amount %v, want %v", got.BaseQuantity*got.BaseUnitPrice, extended)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	str "strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_APPROVED_VENDOR = "approvedVendor"
+	VENDOR_STATUS_APPROVED   = "approved"
+	VENDOR_STATUS_REVOKED    = "revoked"
+)
+
+/*
+	Method: setApprovedVendor
+	Executed when the customer qualifies, renews or revokes a manufacturer for a material group
+*/
+func (s *SmartContract) setApprovedVendor(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1. 1. approved vendor")
+	}
+	vendor := ApprovedVendor{}
+	err := json.Unmarshal([]byte(args[0]), &vendor)
+	if err != nil {
+		return shim.Error("Unable to parse approved vendor data provided - " + args[0])
+	}
+	if vendor.Manufacturer == "" || vendor.MaterialGroup == "" {
+		return shim.Error("manufacturer and materialGroup are required.")
+	}
+	vendor.Status = str.ToLower(vendor.Status)
+	if vendor.Status == "" {
+		vendor.Status = VENDOR_STATUS_APPROVED
+	}
+	if vendor.Status != VENDOR_STATUS_APPROVED && vendor.Status != VENDOR_STATUS_REVOKED {
+		return shim.Error("Expecting approved or revoked for status. Found: " + vendor.Status)
+	}
+	if vendor.ExpiryTimeStamp != 0 && vendor.ExpiryTimeStamp <= vendor.QualifiedTimeStamp {
+		return shim.Error("Qualification expiry must be after the qualification date.")
+	}
+	vendor.ObjectType = DOC_TYPE_APPROVED_VENDOR
+	vendorKey, err := stub.CreateCompositeKey(DOC_TYPE_APPROVED_VENDOR, []string{str.ToLower(vendor.MaterialGroup), str.ToLower(vendor.Spec), str.ToLower(vendor.Manufacturer)})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	vendorBytes, _ := json.Marshal(vendor)
+	err = stub.PutPrivateData(PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR, vendorKey, vendorBytes)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(vendorBytes)
+}
+
+/*
+	Method: queryApprovedVendors
+	Returns the approved vendor list, optionally for a single material group
+*/
+func (s *SmartContract) queryApprovedVendors(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+	materialGroup := ""
+	if len(args) > 0 {
+		materialGroup = args[0]
+	}
+	vendors, err := getApprovedVendors(stub, materialGroup)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	vendorBytes, _ := json.Marshal(vendors)
+	return shim.Success(vendorBytes)
+}
+
+/*
+	Method: queryEligibleManufacturers
+	Returns every manufacturer with whether it can supply a given purchase order line
+*/
+func (s *SmartContract) queryEligibleManufacturers(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. poId 2. lineNumber 3. timeStamp")
+	}
+	poId := args[0]
+	lineNumber, err := strconv.Atoi(args[1])
+	if err != nil {
+		return shim.Error("Unable to parse line number provided - " + args[1] + " Expecting a number.")
+	}
+	timeStamp, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse timestamp provided - " + args[2] + " Expecting a number.")
+	}
+	poPrivateDataResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, poId)
+	if err != nil || poPrivateDataResponse == nil {
+		return shim.Error("No records found for PO: " + poId)
+	}
+	itemPrivateData := LineItemPrivateDetails{}
+	json.Unmarshal(poPrivateDataResponse, &itemPrivateData)
+	for _, lineItem := range itemPrivateData.LineItems {
+		if lineItem.LineNumber != lineNumber {
+			continue
+		}
+		manufacturers := make([]EligibleManufacturer, 0)
+		for _, mspId := range []string{"org3msp", "org4msp"} {
+			manufacturers = append(manufacturers, checkApprovedVendor(stub, lineItem, organizationMap[mspId], timeStamp))
+		}
+		manufacturerBytes, _ := json.Marshal(manufacturers)
+		return shim.Success(manufacturerBytes)
+	}
+	return shim.Error(fmt.Sprintf("Line %d not found for PO: %s", lineNumber, poId))
+}
+
+/*
+	Method: checkApprovedVendor
+	Checks the catalog and the approved vendor list for whether a manufacturer can supply a line at a point in time
+*/
+func checkApprovedVendor(stub shim.ChaincodeStubInterface, lineItem LineItem, manufacturer string, timeStamp int64) EligibleManufacturer {
+	result := EligibleManufacturer{Manufacturer: manufacturer}
+	spec := ""
+	if material, found, _ := getMaterial(stub, lineItem.MaterialId); found {
+		spec = materialSpecName(material.Spec)
+	}
+	if !approvedByCatalog(stub, lineItem.MaterialId, manufacturer) {
+		result.Reason = fmt.Sprintf("%s is not an approved manufacturer of material %s", manufacturer, lineItem.MaterialId)
+		return result
+	}
+	vendors, err := getApprovedVendors(stub, lineItem.MaterialGroup)
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	result.Reason = fmt.Sprintf("%s is not on the approved vendor list for %s %s", manufacturer, lineItem.MaterialGroup, spec)
+	for _, vendor := range vendors {
+		if !str.EqualFold(vendor.Manufacturer, manufacturer) || (vendor.Spec != "" && !str.EqualFold(vendor.Spec, spec)) {
+			continue
+		}
+		switch {
+		case vendor.Status != VENDOR_STATUS_APPROVED:
+			result.Reason = fmt.Sprintf("%s qualification for %s has been revoked", manufacturer, lineItem.MaterialGroup)
+		case vendor.QualifiedTimeStamp > timeStamp:
+			result.Reason = fmt.Sprintf("%s qualification for %s is not yet effective", manufacturer, lineItem.MaterialGroup)
+		case vendor.ExpiryTimeStamp != 0 && vendor.ExpiryTimeStamp <= timeStamp:
+			result.Reason = fmt.Sprintf("%s qualification for %s expired", manufacturer, lineItem.MaterialGroup)
+			result.ExpiryTimeStamp = vendor.ExpiryTimeStamp
+		default:
+			result.Eligible = true
+			result.Reason = ""
+			result.ExpiryTimeStamp = vendor.ExpiryTimeStamp
+			return result
+		}
+	}
+	return result
+}
+
+/*
+	Method: approvedByCatalog
+	Checks the approved manufacturers of a catalog material; materials without the list or not in the catalog allow any manufacturer
+*/
+func approvedByCatalog(stub shim.ChaincodeStubInterface, materialId string, manufacturer string) bool {
+	material, found, _ := getMaterial(stub, materialId)
+	return !found || len(material.ApprovedManufacturers) == 0 || containsFold(material.ApprovedManufacturers, manufacturer)
+}
+
+/*
+	Method: getApprovedVendors
+	Returns the approved vendor entries of a material group, or all entries when the group is empty
+*/
+func getApprovedVendors(stub shim.ChaincodeStubInterface, materialGroup string) ([]ApprovedVendor, error) {
+	keys := []string{}
+	if materialGroup != "" {
+		keys = []string{str.ToLower(materialGroup)}
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(PRIVATE_COLLECTION_CUSTOMER_DISTRIBUTOR, DOC_TYPE_APPROVED_VENDOR, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	vendors := make([]ApprovedVendor, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		vendor := ApprovedVendor{}
+		json.Unmarshal(queryResponse.Value, &vendor)
+		vendors = append(vendors, vendor)
+	}
+	return vendors, nil
+}
+
+func materialSpecName(spec MaterialSpec) string {
+	return str.TrimSpace(spec.Standard + " " + spec.Grade)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, each := range values {
+		if str.EqualFold(each, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+/*
+	Method: verifyPrivateData
+	Checks a presented document against the hash of the private data stored under a collection and key.
+	Any channel member can call it, including orgs that are not members of the collection,
+	since only the hash is read from the ledger.
+*/
+func (s *SmartContract) verifyPrivateData(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3. 1. private collection name 2. key 3. document")
+	}
+	verification, err := checkPrivateDataHash(stub, args[0], args[1], []byte(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	verificationBytes, _ := json.Marshal(verification)
+	return shim.Success(verificationBytes)
+}
+
+/*
+	Method: queryPrivateDataHash
+	Returns the hex encoded hash of the private data stored under a collection and key
+*/
+func (s *SmartContract) queryPrivateDataHash(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 2 {
+		return shim.Error("Incorrect number of arguments. Expecting 2. 1. private collection name 2. key")
+	}
+	onChainHash, err := stub.GetPrivateDataHash(args[0], args[1])
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if onChainHash == nil {
+		return shim.Error("Not Found")
+	}
+	verification := PrivateDataVerification{Collection: args[0], Key: args[1], OnChainHash: hex.EncodeToString(onChainHash)}
+	verificationBytes, _ := json.Marshal(verification)
+	return shim.Success(verificationBytes)
+}
+
+/*
+	Method: checkPrivateDataHash
+	Canonicalizes a presented document and compares its SHA-256 hash with the on-chain hash
+*/
+func checkPrivateDataHash(stub shim.ChaincodeStubInterface, collection string, key string, document []byte) (PrivateDataVerification, error) {
+	verification := PrivateDataVerification{Collection: collection, Key: key}
+	onChainHash, err := stub.GetPrivateDataHash(collection, key)
+	if err != nil {
+		return verification, err
+	}
+	if onChainHash == nil {
+		return verification, fmt.Errorf("no private data hash found for key %s in collection %s", key, collection)
+	}
+	canonicalDocument, err := canonicalPrivateDocument(document)
+	if err != nil {
+		return verification, err
+	}
+	presentedHash := sha256.Sum256(canonicalDocument)
+	verification.OnChainHash = hex.EncodeToString(onChainHash)
+	verification.PresentedHash = hex.EncodeToString(presentedHash[:])
+	verification.Matches = verification.OnChainHash == verification.PresentedHash
+	return verification, nil
+}
+
+/*
+	Method: canonicalPrivateDocument
+	Returns the bytes a presented document is hashed over. The chaincode stores documents as
+	compact JSON, so only insignificant whitespace is removed; field order and values must be
+	exactly those of the stored document, as returned by the query paths.
+*/
+func canonicalPrivateDocument(document []byte) ([]byte, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, document); err != nil {
+		return nil, fmt.Errorf("unable to parse document provided - %s", err.Error())
+	}
+	return compacted.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalPrivateDocument(t *testing.T) {
+	// a certificate stored before spec, compliance and document were added
+	stored := []byte(`{"docType":"collectionMtrManufacturer1","heatNumber":"H123","materialId":"M-1","data":{"C":"<= 0.26"}}`)
+	tests := []struct {
+		name      string
+		presented string
+		want      string
+		wantErr   bool
+	}{
+		{"stored bytes", string(stored), string(stored), false},
+		{"indented", "{\n  \"docType\": \"collectionMtrManufacturer1\",\n  \"heatNumber\": \"H123\",\n  \"materialId\": \"M-1\",\n  \"data\": {\"C\": \"<= 0.26\"}\n}\n", string(stored), false},
+		{"field order kept", `{"heatNumber":"H123","docType":"collectionMtrManufacturer1"}`, `{"heatNumber":"H123","docType":"collectionMtrManufacturer1"}`, false},
+		{"numbers kept", `{"quantity": 1.50, "price": 1e2}`, `{"quantity":1.50,"price":1e2}`, false},
+		{"unknown docType", `{ "docType": "shippingRequestPo", "poId": "PO1" }`, `{"docType":"shippingRequestPo","poId":"PO1"}`, false},
+		{"not json", `{"docType":`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := canonicalPrivateDocument([]byte(tt.presented))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalPrivateDocumentMatchesMarshal(t *testing.T) {
+	// the chaincode writes documents with json.Marshal, so a stored document is already canonical
+	stored, _ := json.Marshal(LineItemPrivateDetails{ObjectType: PRIVATE_COLLECTION_CUSTOMER_LINEITEMS, PoId: "PO1", LineItems: []LineItem{{LineNumber: 1, MaterialId: "M-1"}}})
+	presented, _ := json.MarshalIndent(json.RawMessage(stored), "", "\t")
+	got, err := canonicalPrivateDocument(presented)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(stored) {
+		t.Errorf("got %s, want %s", got, stored)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	sc "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	DOC_TYPE_WAREHOUSE_ITEM = "warehouseItem"
+)
+
+/*
+	Method: warehouseCollection
+	Returns the private collection and name of a warehouse from its MSP id
+*/
+func warehouseCollection(mspId string) (string, string) {
+	switch mspId {
+	case "org6msp": // "Warehouse 1"
+		return PRIVATE_COLLECTION_WAREHOUSE1, organizationMap["org6msp"]
+	case "org7msp": // "Warehouse 2"
+		return PRIVATE_COLLECTION_WAREHOUSE2, organizationMap["org7msp"]
+	}
+	return "", ""
+}
+
+/*
+	Method: warehouseReceive
+	Executed when a warehouse receives an inbound manufacturer shipment.
+	The lines must be on the PO and cannot be received twice into the same warehouse.
+*/
+func (s *SmartContract) warehouseReceive(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. poId 2. lineItems 3. received from 4. progress status")
+	}
+	poId := args[0]
+	lineItems := []LineItem{}
+	err := json.Unmarshal([]byte(args[1]), &lineItems)
+	if err != nil {
+		return shim.Error("Unable to parse lineItem data provided - " + args[1])
+	}
+	progressStatus := ItemStatus{}
+	err = json.Unmarshal([]byte(args[3]), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + args[3])
+	}
+	privateCollection, warehouse := warehouseCollection(currentMspId)
+	if privateCollection == "" {
+		return shim.Error("Unexpected Organization Id - " + currentMspId)
+	}
+	if value, err := stub.GetState(poId); err != nil || value == nil {
+		return shim.Error("Purchase order not found - " + poId)
+	}
+	progressResponse, err := stub.GetPrivateData(PRIVATE_COLLECTION_GENERAL_PROGRESS, poId)
+	if err != nil || progressResponse == nil {
+		return shim.Error("Shared progress record not found for - " + poId)
+	}
+	sharedProgress := SharedProgressReport{}
+	json.Unmarshal(progressResponse, &sharedProgress)
+	poItemKeys := make(map[string]int)
+	for _, line := range sharedProgress.LineItems {
+		poItemKeys[line.ItemKey] = line.LineNumber
+	}
+	progressStatus.Status = STATUS_WAREHOUSE_RECEIVED
+	if progressStatus.Owner == "" {
+		progressStatus.Owner = warehouse
+	}
+	items := make([]WarehouseItem, 0)
+	sharedItemsMap := make(map[int]LineItem)
+	for _, lineItem := range lineItems {
+		if lineItem.ItemKey == "" {
+			return shim.Error(fmt.Sprintf("itemKey is required for line %d", lineItem.LineNumber))
+		}
+		if lineNumber, found := poItemKeys[lineItem.ItemKey]; !found || lineNumber != lineItem.LineNumber {
+			return shim.Error(fmt.Sprintf("Line %d with itemKey %s not found on PO %s", lineItem.LineNumber, lineItem.ItemKey, poId))
+		}
+		itemKeyComposite, err := stub.CreateCompositeKey(DOC_TYPE_WAREHOUSE_ITEM, []string{poId, lineItem.ItemKey})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		// lines received earlier in this call are not visible to the private data read
+		if _, received := sharedItemsMap[lineItem.LineNumber]; received {
+			return Error(http.StatusConflict, fmt.Sprintf("line %d is listed more than once", lineItem.LineNumber))
+		}
+		if value, err := stub.GetPrivateData(privateCollection, itemKeyComposite); !(err == nil && value == nil) {
+			return Error(http.StatusConflict, fmt.Sprintf("warehouse item %s of PO %s exists", lineItem.ItemKey, poId))
+		}
+		item := WarehouseItem{
+			ObjectType:      DOC_TYPE_WAREHOUSE_ITEM,
+			Warehouse:       warehouse,
+			PoId:            poId,
+			PoNumber:        lineItem.PoNumber,
+			LineNumber:      lineItem.LineNumber,
+			ItemKey:         lineItem.ItemKey,
+			MaterialId:      lineItem.MaterialId,
+			Description:     lineItem.Description,
+			Quantity:        lineItem.Quantity,
+			UnitOfMeasure:   lineItem.UnitOfMeasure,
+			ReceivedFrom:    args[2],
+			IotTrackingCode: lineItem.IotTrackingCode,
+			Status:          STATUS_WAREHOUSE_RECEIVED,
+			TimeReceived:    progressStatus.TimeStamp,
+			ProgressStatus:  []ItemStatus{progressStatus},
+		}
+		err = commitWarehouseItem(stub, privateCollection, item)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		items = append(items, item)
+		sharedItemsMap[item.LineNumber] = LineItem{LineNumber: item.LineNumber, Warehouse: warehouse}
+	}
+	// Add progress to shared table
+	updateSharedProgressRecord(stub, poId, sharedItemsMap, progressStatus, MODE_WAREHOUSE)
+	itemBytes, _ := json.Marshal(items)
+	return shim.Success(itemBytes)
+}
+
+/*
+	Method: warehousePutAway
+	Executed when a warehouse stores a received line item in a bin location
+*/
+func (s *SmartContract) warehousePutAway(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4. 1. poId 2. itemKey 3. bin location 4. progress status")
+	}
+	if args[2] == "" {
+		return shim.Error("Bin location is required.")
+	}
+	return updateWarehouseItem(stub, args[0], args[1], args[3], STATUS_PUT_AWAY, []string{STATUS_WAREHOUSE_RECEIVED, STATUS_PUT_AWAY}, func(item *WarehouseItem) {
+		item.BinLocation = args[2]
+	})
+}
+
+/*
+	Method: warehousePick
+	Executed when a warehouse picks a stored line item and stages it for an outbound shipping request
+*/
+func (s *SmartContract) warehousePick(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	if len(args) != 5 {
+		return shim.Error("Incorrect number of arguments. Expecting 5. 1. poId 2. itemKey 3. shippingRequestNumber 4. staging location 5. progress status")
+	}
+	shippingRequestNumber, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return shim.Error("Unable to parse shippingRequestNumber provided - " + args[2] + " Expecting an int64 number.")
+	}
+	return updateWarehouseItem(stub, args[0], args[1], args[4], STATUS_STAGED, []string{STATUS_WAREHOUSE_RECEIVED, STATUS_PUT_AWAY}, func(item *WarehouseItem) {
+		item.ShippingRequestNumber = shippingRequestNumber
+		item.StagingLocation = args[3]
+	})
+}
+
+/*
+	Method: queryWarehouseItems
+	Returns the items held by a warehouse, optionally for a single purchase order.
+	Warehouses see their own items, distributor and logistics name the warehouse to look at.
+*/
+func (s *SmartContract) queryWarehouseItems(stub shim.ChaincodeStubInterface, args []string) sc.Response {
+
+	privateCollection, _ := warehouseCollection(currentMspId)
+	if privateCollection == "" {
+		if len(args) < 1 {
+			return shim.Error("Incorrect number of arguments. Expecting 1. warehouse msp id 2. poId (optional)")
+		}
+		privateCollection, _ = warehouseCollection(args[0])
+	}
+	if privateCollection == "" {
+		return shim.Error("Unknown warehouse - " + args[0])
+	}
+	keys := []string{}
+	if len(args) > 1 && args[1] != "" {
+		keys = []string{args[1]}
+	}
+	resultsIterator, err := stub.GetPrivateDataByPartialCompositeKey(privateCollection, DOC_TYPE_WAREHOUSE_ITEM, keys)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	defer resultsIterator.Close()
+	items := make([]WarehouseItem, 0)
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		item := WarehouseItem{}
+		json.Unmarshal(queryResponse.Value, &item)
+		items = append(items, item)
+	}
+	itemBytes, _ := json.Marshal(items)
+	return shim.Success(itemBytes)
+}
+
+/*
+	Method: updateWarehouseItem
+	Moves a warehouse item to a new status, recording progress in the warehouse collection and the shared record
+*/
+func updateWarehouseItem(stub shim.ChaincodeStubInterface, poId string, itemKey string, progressArg string, status string, validFrom []string, update func(item *WarehouseItem)) sc.Response {
+	progressStatus := ItemStatus{}
+	err := json.Unmarshal([]byte(progressArg), &progressStatus)
+	if err != nil {
+		return shim.Error("Unable to parse progress status data provided - " + progressArg)
+	}
+	privateCollection, _ := warehouseCollection(currentMspId)
+	if privateCollection == "" {
+		return shim.Error("Unexpected Organization Id - " + currentMspId)
+	}
+	itemKeyComposite, err := stub.CreateCompositeKey(DOC_TYPE_WAREHOUSE_ITEM, []string{poId, itemKey})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	itemResponse, err := stub.GetPrivateData(privateCollection, itemKeyComposite)
+	if err != nil || itemResponse == nil {
+		return shim.Error("Warehouse item not found for - " + itemKey)
+	}
+	item := WarehouseItem{}
+	json.Unmarshal(itemResponse, &item)
+	if !containsFold(validFrom, item.Status) {
+		return shim.Error(fmt.Sprintf("Warehouse item %s is %s, cannot move to %s", itemKey, item.Status, status))
+	}
+	update(&item)
+	progressStatus.Status = status
+	if progressStatus.Owner == "" {
+		progressStatus.Owner = item.Warehouse
+	}
+	item.Status = status
+	item.ProgressStatus = append(item.ProgressStatus, progressStatus)
+	err = commitWarehouseItem(stub, privateCollection, item)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	// Add progress to shared table
+	sharedItemsMap := map[int]LineItem{item.LineNumber: {LineNumber: item.LineNumber, Warehouse: item.Warehouse}}
+	updateSharedProgressRecord(stub, poId, sharedItemsMap, progressStatus, MODE_WAREHOUSE)
+	itemBytes, _ := json.Marshal(item)
+	return shim.Success(itemBytes)
+}
+
+/*
+	Method: commitWarehouseItem
+	Utility method to commit a warehouse item into the warehouse private collection
+*/
+func commitWarehouseItem(stub shim.ChaincodeStubInterface, privateCollection string, item WarehouseItem) error {
+	itemKey, err := stub.CreateCompositeKey(DOC_TYPE_WAREHOUSE_ITEM, []string{item.PoId, item.ItemKey})
+	if err != nil {
+		return err
+	}
+	itemBytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return stub.PutPrivateData(privateCollection, itemKey, itemBytes)
+}
@@ -14,6 +14,34 @@ type CustomEvent struct {
 	TimeStamp   int64      `json:"timeStamp"`
 }
 
+/*
+	Defines a structure for the event emitted when a recall is opened or closed
+*/
+type RecallEvent struct {
+	Type             string   `json:"type"`
+	Description      string   `json:"description"`
+	Status           string   `json:"status"`
+	RecallId         string   `json:"recallId"`
+	TrackingId       string   `json:"trackingId"`
+	HeatNumber       string   `json:"heatNumber"`
+	AffectedOrgs     []string `json:"affectedOrgs"`
+	AffectedItemKeys []string `json:"affectedItemKeys"`
+}
+
+/*
+	Defines a structure for the event emitted when a load is tendered, answered or awarded
+*/
+type ShipmentTenderEvent struct {
+	Type                  string   `json:"type"`
+	Description           string   `json:"description"`
+	Status                string   `json:"status"`
+	ShippingRequestNumber int64    `json:"shippingRequestNumber"`
+	PoId                  string   `json:"poId"`
+	PoNumber              int      `json:"poNumber"`
+	Carriers              []string `json:"carriers"`
+	TimeStamp             int64    `json:"timeStamp"`
+}
+
 /*
 	Defines a structure for event emitted when it's determined an item has
 	arrived to specific destination.  This is determined by based on
@@ -30,6 +58,8 @@ type ItemDeliveryEvent struct {
 	ShippingRequestNumber int64                    `json:"shippingRequestNumber"`
 	ShippedLineItems      []ShippingLineItem       `json:"shippedLineItems"`
 	ProgressStatus        ItemStatus               `json:"progressStatus"`
+	LoadId                string                   `json:"loadId,omitempty"`
+	Deliveries            []ItemDeliveryEvent      `json:"deliveries,omitempty"` // one per PO on a consolidated load
 }
 
 /*
@@ -54,8 +84,23 @@ type ManufacturerPricingDiscount struct {
   Defines a structure for goods receipt used for integration with SAP
 */
 type GoodReceipt struct {
-	MaterialCertificate []Mtr            `json:"materialCertificate"`
-	ShippedLineItem     ShippingLineItem `json:"shippedLineItem"`
+	MaterialCertificate  []Mtr            `json:"materialCertificate"`
+	ShippedLineItem      ShippingLineItem `json:"shippedLineItem"`
+	OrderedBaseQuantity  float64          `json:"orderedBaseQuantity"`
+	ReceivedBaseQuantity float64          `json:"receivedBaseQuantity"`
+	BaseUnitOfMeasure    string           `json:"baseUnitOfMeasure"`
+	QuantityVariance     float64          `json:"quantityVariance"` // received less ordered, in base units
+}
+
+/*
+	Defines a structure for the result of a unit of measure conversion
+*/
+type UnitConversionResult struct {
+	MaterialId             string  `json:"materialId"`
+	Quantity               float64 `json:"quantity"`
+	UnitOfMeasure          string  `json:"unitOfMeasure"`
+	ConvertedQuantity      float64 `json:"convertedQuantity"`
+	ConvertedUnitOfMeasure string  `json:"convertedUnitOfMeasure"`
 }
 
 /*
@@ -94,15 +139,82 @@ type ShippingRequestsResults struct {
 	Owner                Company            `json:"owner"`
 	ExpectedDeliveryDate string             `json:"expectedDeliveryDate"`
 	LineItems            []ShippingLineItem `json:"lineItems"`
+	Tenders              []ShipmentTender   `json:"tenders,omitempty"` // set on a carrier's loads
 }
 
 /*
 	Defines a structure for ProjectManager screen Data
 */
 type PmView struct {
-	PoId      string     `json:"poId"`
-	PoNumber  int        `json:"poNumber"`
-	PoStatus  string     `json:"poStatus"`
-	ProjectId string     `json:"projectId"`
-	LineItems []LineItem `json:"lineItems"`
+	PoId          string               `json:"poId"`
+	PoNumber      int                  `json:"poNumber"`
+	PoStatus      string               `json:"poStatus"`
+	ProjectId     string               `json:"projectId"`
+	ProjectBudget ProjectBudgetSummary `json:"projectBudget"`
+	LineItems     []LineItem           `json:"lineItems"`
+}
+
+/*
+	Defines a structure for the distributor margin report
+*/
+type MarginReport struct {
+	FromTimeStamp   int64           `json:"fromTimeStamp"`
+	ToTimeStamp     int64           `json:"toTimeStamp"`
+	Currency        string          `json:"currency"`
+	Total           MarginSummary   `json:"total"`
+	ByPo            []MarginSummary `json:"byPo"`
+	ByProject       []MarginSummary `json:"byProject"`
+	ByManufacturer  []MarginSummary `json:"byManufacturer"`
+	ByMaterialGroup []MarginSummary `json:"byMaterialGroup"`
+}
+
+/*
+	Revenue, cost and margin totals for one grouping of the margin report
+*/
+type MarginSummary struct {
+	Key           string  `json:"key"`
+	Revenue       float64 `json:"revenue"`
+	Cost          float64 `json:"cost"`
+	Margin        float64 `json:"margin"`
+	MarginPercent float64 `json:"marginPercent"`
+}
+
+/*
+	Defines the result of checking a presented document against the hash of
+	private data recorded on the ledger
+*/
+type PrivateDataVerification struct {
+	Collection    string `json:"collection"`
+	Key           string `json:"key"`
+	Matches       bool   `json:"matches"`
+	OnChainHash   string `json:"onChainHash"`
+	PresentedHash string `json:"presentedHash"`
+}
+
+/*
+	Defines the result of checking a stored certificate against its manufacturer signature
+*/
+type MtrSignatureVerification struct {
+	TrackingId                 string `json:"trackingId"`
+	Revision                   int    `json:"revision"`
+	Authentic                  bool   `json:"authentic"`
+	ContentMatches             bool   `json:"contentMatches"`
+	SignatureValid             bool   `json:"signatureValid"`
+	CertificateValidWhenSigned bool   `json:"certificateValidWhenSigned"`
+	ContentSha256              string `json:"contentSha256"`
+	SignerMspId                string `json:"signerMspId"`
+	SignerSubject              string `json:"signerSubject"`
+	SignedTimeStamp            int64  `json:"signedTimeStamp"`
+	Error                      string `json:"error,omitempty"`
+}
+
+/*
+	Defines the result of checking a file against the document hash recorded on a material certificate
+*/
+type MtrDocumentVerification struct {
+	TrackingId    string `json:"trackingId"`
+	Uri           string `json:"uri"`
+	Matches       bool   `json:"matches"`
+	OnChainHash   string `json:"onChainHash"`
+	PresentedHash string `json:"presentedHash"`
 }